@@ -41,7 +41,7 @@ func main() {
 				if nx%2 == 0 {
 					return gosteps.BranchName("divide")
 				}
-				return gosteps.BranchName("multiple")
+				return gosteps.BranchName("multiply")
 			},
 			Branches: []gosteps.Branch{
 				{
@@ -0,0 +1,150 @@
+package gosteps
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// jitterRand is the random source for the jittered backoffs
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// jitterRandMutex guards the jitterRand, as rand.Rand is not safe for concurrent use
+	jitterRandMutex sync.Mutex
+)
+
+// Backoff interface defines the strategy to compute the
+// sleep duration between the retries of a step
+type Backoff interface {
+	// Delay returns the sleep duration before the next attempt, attempt is the number of
+	// attempts already run (1 before the first retry) and previousDelay is the duration
+	// slept before the previous attempt (0 before the first retry)
+	Delay(attempt int, previousDelay time.Duration) time.Duration
+}
+
+// ConstantBackoff sleeps for the same Interval between each retry
+type ConstantBackoff struct {
+	Interval time.Duration
+}
+
+// LinearBackoff sleeps for Initial before the first retry,
+// and increases the sleep by Increment for each retry
+type LinearBackoff struct {
+	Initial   time.Duration
+	Increment time.Duration
+}
+
+// ExponentialBackoff sleeps for Initial before the first retry, and
+// multiplies the sleep by Multiplier for each retry, defaults to 2
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Multiplier float64
+}
+
+// ExponentialJitterBackoff sleeps for a random duration between 0 and
+// the ExponentialBackoff duration of the retry, ie. "full jitter"
+type ExponentialJitterBackoff struct {
+	Initial    time.Duration
+	Multiplier float64
+}
+
+// DecorrelatedJitterBackoff sleeps for a random duration between Base
+// and three times the previous sleep, ie. "decorrelated jitter"
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+}
+
+// Delay returns the constant interval
+func (b ConstantBackoff) Delay(attempt int, previousDelay time.Duration) time.Duration {
+	return b.Interval
+}
+
+// Delay returns the linearly increasing delay
+func (b LinearBackoff) Delay(attempt int, previousDelay time.Duration) time.Duration {
+	retries := attempt - 1
+	if retries < 0 {
+		retries = 0
+	}
+
+	return durationOf(float64(b.Initial) + float64(b.Increment)*float64(retries))
+}
+
+// Delay returns the exponentially increasing delay
+func (b ExponentialBackoff) Delay(attempt int, previousDelay time.Duration) time.Duration {
+	return exponentialDelay(b.Initial, b.Multiplier, attempt)
+}
+
+// Delay returns a random delay up to the exponentially increasing delay
+func (b ExponentialJitterBackoff) Delay(attempt int, previousDelay time.Duration) time.Duration {
+	return randomDuration(0, exponentialDelay(b.Initial, b.Multiplier, attempt))
+}
+
+// Delay returns a random delay between the base and three times the previous delay
+func (b DecorrelatedJitterBackoff) Delay(attempt int, previousDelay time.Duration) time.Duration {
+	if previousDelay < b.Base {
+		previousDelay = b.Base
+	}
+
+	return randomDuration(b.Base, durationOf(float64(previousDelay)*3))
+}
+
+// exponentialDelay returns initial * multiplier^(attempt-1)
+func exponentialDelay(initial time.Duration, multiplier float64, attempt int) time.Duration {
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+
+	retries := attempt - 1
+	if retries < 0 {
+		retries = 0
+	}
+
+	return durationOf(float64(initial) * math.Pow(multiplier, float64(retries)))
+}
+
+// durationOf converts the float to a duration, without overflowing
+func durationOf(d float64) time.Duration {
+	if d >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return time.Duration(d)
+}
+
+// randomDuration returns a random duration in [min, max)
+func randomDuration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+
+	jitterRandMutex.Lock()
+	defer jitterRandMutex.Unlock()
+
+	return min + time.Duration(jitterRand.Int63n(int64(max-min)))
+}
+
+// retrySleep returns the sleep duration before the next attempt of the step,
+// the retry delay suggested by the step result overrides the computed one
+func (step *Step) retrySleep() time.Duration {
+	if step.stepResult != nil && step.stepResult.RetryAfter != nil {
+		return *step.stepResult.RetryAfter
+	}
+
+	backoff := step.StepOpts.Backoff
+	if backoff == nil {
+		backoff = ConstantBackoff{Interval: step.StepOpts.RetrySleep}
+	}
+
+	delay := backoff.Delay(step.stepRunProgress.runCount, step.stepRunProgress.lastSleep)
+	if step.StepOpts.MaxRetrySleep > 0 && delay > step.StepOpts.MaxRetrySleep {
+		delay = step.StepOpts.MaxRetrySleep
+	}
+
+	return delay
+}
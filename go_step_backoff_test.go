@@ -0,0 +1,139 @@
+package gosteps
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BackoffDelay(t *testing.T) {
+
+	testCases := []struct {
+		Backoff       Backoff
+		Attempt       int
+		ExpectedDelay time.Duration
+	}{
+		{
+			Backoff:       ConstantBackoff{Interval: time.Second},
+			Attempt:       5,
+			ExpectedDelay: time.Second,
+		},
+		{
+			Backoff:       LinearBackoff{Initial: time.Second, Increment: 2 * time.Second},
+			Attempt:       1,
+			ExpectedDelay: time.Second,
+		},
+		{
+			Backoff:       LinearBackoff{Initial: time.Second, Increment: 2 * time.Second},
+			Attempt:       3,
+			ExpectedDelay: 5 * time.Second,
+		},
+		{
+			Backoff:       ExponentialBackoff{Initial: time.Second},
+			Attempt:       1,
+			ExpectedDelay: time.Second,
+		},
+		{
+			Backoff:       ExponentialBackoff{Initial: time.Second},
+			Attempt:       4,
+			ExpectedDelay: 8 * time.Second,
+		},
+		{
+			Backoff:       ExponentialBackoff{Initial: time.Second, Multiplier: 3},
+			Attempt:       3,
+			ExpectedDelay: 9 * time.Second,
+		},
+		{
+			Backoff:       ExponentialBackoff{Initial: time.Second},
+			Attempt:       1000,
+			ExpectedDelay: time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.ExpectedDelay, tc.Backoff.Delay(tc.Attempt, 0))
+	}
+}
+
+func Test_JitterBackoffDelay(t *testing.T) {
+
+	exponentialJitter := ExponentialJitterBackoff{Initial: time.Second}
+	decorrelatedJitter := DecorrelatedJitterBackoff{Base: time.Second}
+
+	for i := 0; i < 100; i++ {
+		delay := exponentialJitter.Delay(3, 0)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.Less(t, delay, 4*time.Second)
+
+		delay = decorrelatedJitter.Delay(3, 2*time.Second)
+		assert.GreaterOrEqual(t, delay, time.Second)
+		assert.Less(t, delay, 6*time.Second)
+	}
+}
+
+func Test_retrySleep(t *testing.T) {
+
+	retryAfter := 3 * time.Second
+
+	testCases := []struct {
+		Step          Step
+		ExpectedSleep time.Duration
+	}{
+		{
+			Step: Step{
+				StepOpts: StepOpts{
+					RetrySleep: time.Second,
+				},
+				stepRunProgress: StepRunProgress{
+					runCount: 3,
+				},
+			},
+			ExpectedSleep: time.Second,
+		},
+		{
+			Step: Step{
+				StepOpts: StepOpts{
+					RetrySleep: time.Second,
+					Backoff:    ExponentialBackoff{Initial: time.Second},
+				},
+				stepRunProgress: StepRunProgress{
+					runCount: 3,
+				},
+			},
+			ExpectedSleep: 4 * time.Second,
+		},
+		{
+			Step: Step{
+				StepOpts: StepOpts{
+					Backoff:       ExponentialBackoff{Initial: time.Second},
+					MaxRetrySleep: 3 * time.Second,
+				},
+				stepRunProgress: StepRunProgress{
+					runCount: 3,
+				},
+			},
+			ExpectedSleep: 3 * time.Second,
+		},
+		{
+			Step: Step{
+				StepOpts: StepOpts{
+					Backoff: ExponentialBackoff{Initial: time.Second},
+				},
+				stepResult: &StepResult{
+					StepState:  StepStatePending,
+					RetryAfter: &retryAfter,
+				},
+				stepRunProgress: StepRunProgress{
+					runCount: 1,
+				},
+			},
+			ExpectedSleep: retryAfter,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.ExpectedSleep, tc.Step.retrySleep())
+	}
+}
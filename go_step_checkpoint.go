@@ -0,0 +1,481 @@
+package gosteps
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCheckpointNotFound is the error of a run without a checkpoint in the store
+	ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+	// ErrNoCheckpointer is the error of a resume with a context without a Checkpointer
+	ErrNoCheckpointer = errors.New("no checkpointer in the context")
+)
+
+// RunID type defines the id of a step-chain run, used as the key of its checkpoint,
+// set it in the context with Use, example: ctx.Use(gosteps.RunID("order-42"))
+type RunID string
+
+// Checkpoint type defines the state of a step-chain run, saved after each step
+type Checkpoint struct {
+	RunID     RunID                   `json:"runId"`
+	Data      GoStepsCtxData          `json:"data"`      // data of the context
+	Completed map[string]StepProgress `json:"completed"` // progress of the completed steps, by position
+	Branches  map[string]BranchName   `json:"branches"`  // branches selected by the steps, by position
+	Position  string                  `json:"position"`  // position of the last executed step
+	UpdatedAt time.Time               `json:"updatedAt"`
+}
+
+// CheckpointStore interface defines the storage of the checkpoints, by run id
+type CheckpointStore interface {
+	Save(runID RunID, checkpoint []byte) error
+	Load(runID RunID) ([]byte, error) // returns ErrCheckpointNotFound if there is no checkpoint
+	Delete(runID RunID) error
+}
+
+// Codec interface defines the serialization of the checkpoints, including the values of the context data
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec type defines a codec serializing the checkpoints as JSON, values of the
+// context data are loaded as their JSON types, numbers are loaded as int or float64
+type JSONCodec struct{}
+
+// Marshal serializes the value as JSON
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal deserializes the JSON data into the value, numbers are decoded as json.Number
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+// GobCodec type defines a codec serializing the checkpoints with encoding/gob, values of the
+// context data keep their types, custom types must be registered with gob.Register
+type GobCodec struct{}
+
+func init() {
+	// nested maps and slices, as decoded from JSON or YAML, are common context data values
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Marshal serializes the value with encoding/gob
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Unmarshal deserializes the encoding/gob data into the value
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Checkpointer type defines how the runs of step-chains are checkpointed,
+// set it in the context with Use, example: ctx.Use(gosteps.NewCheckpointer(store, nil))
+type Checkpointer struct {
+	Store CheckpointStore
+	Codec Codec // JSONCodec if not set
+}
+
+// NewCheckpointer creates a new checkpointer, with the JSONCodec if the codec is nil
+func NewCheckpointer(store CheckpointStore, codec Codec) Checkpointer {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	return Checkpointer{
+		Store: store,
+		Codec: codec,
+	}
+}
+
+// codec returns the codec of the checkpointer, JSONCodec if not set
+func (checkpointer Checkpointer) codec() Codec {
+	if checkpointer.Codec == nil {
+		return JSONCodec{}
+	}
+
+	return checkpointer.Codec
+}
+
+// Save serializes the checkpoint and saves it in the store
+func (checkpointer Checkpointer) Save(checkpoint *Checkpoint) error {
+	data, err := checkpointer.codec().Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("error: failed to encode checkpoint of run [%s]: %w", checkpoint.RunID, err)
+	}
+
+	return checkpointer.Store.Save(checkpoint.RunID, data)
+}
+
+// Load loads the checkpoint of the run from the store, returns
+// an error wrapping ErrCheckpointNotFound if there is no checkpoint
+func (checkpointer Checkpointer) Load(runID RunID) (*Checkpoint, error) {
+	data, err := checkpointer.Store.Load(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &Checkpoint{}
+	if err := checkpointer.codec().Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("error: failed to decode checkpoint of run [%s]: %w", runID, err)
+	}
+
+	checkpoint.normalize()
+
+	return checkpoint, nil
+}
+
+// newCheckpoint returns a new empty checkpoint of the run
+func newCheckpoint(runID RunID) *Checkpoint {
+	checkpoint := &Checkpoint{
+		RunID: runID,
+	}
+	checkpoint.normalize()
+
+	return checkpoint
+}
+
+// normalize initializes the nil maps of the checkpoint, and converts the
+// numbers decoded by the JSONCodec, so that the data matches the original
+func (checkpoint *Checkpoint) normalize() {
+	if checkpoint.Data == nil {
+		checkpoint.Data = GoStepsCtxData{}
+	}
+
+	if checkpoint.Completed == nil {
+		checkpoint.Completed = map[string]StepProgress{}
+	}
+
+	if checkpoint.Branches == nil {
+		checkpoint.Branches = map[string]BranchName{}
+	}
+
+	normalizeNumbers(map[string]interface{}(checkpoint.Data))
+	for position, progress := range checkpoint.Completed {
+		if progress.StepResult.StepData != nil {
+			normalizeNumbers(map[string]interface{}(progress.StepResult.StepData))
+		}
+
+		checkpoint.Completed[position] = progress
+	}
+}
+
+// checkpointRun type defines the checkpointing of a single step-chain run
+type checkpointRun struct {
+	checkpointer Checkpointer
+	checkpoint   *Checkpoint
+}
+
+// checkpoints returns the checkpointing of the run, nil if the run is not checkpointed
+func (run *goStepsRun) checkpoints() *checkpointRun {
+	if run == nil {
+		return nil
+	}
+
+	return run.checkpoint
+}
+
+// newRunID returns a new random run id
+func newRunID() RunID {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return RunID(strconv.FormatInt(time.Now().UnixNano(), 36))
+	}
+
+	return RunID(hex.EncodeToString(id))
+}
+
+// Resume resumes the run of a branch from its checkpoint, with the Checkpointer
+// of the context provided, the steps completed in the run are skipped and the
+// branches selected in the run are taken again, without calling the resolvers
+func (branch *Branch) Resume(c GoStepsContext, runID RunID) *ExecutionReport {
+	return branch.ResumeContext(c.Context(), c, runID)
+}
+
+// ResumeContext resumes the run of a branch from its checkpoint, the
+// step-chain is stopped when the context.Context is done
+func (branch *Branch) ResumeContext(ctx context.Context, c GoStepsContext, runID RunID) *ExecutionReport {
+	goStepsCtx := c.getCtx()
+	goStepsCtx.startRun(ctx)
+	goStepsCtx.run.runID = runID
+
+	fail := func(err error) *ExecutionReport {
+		report := goStepsCtx.run.report()
+		report.Outcome = StepStateFailed
+		report.Error = err
+
+		goStepsCtx.onChainEnd(report)
+		return report
+	}
+
+	if goStepsCtx.checkpointer == nil {
+		return fail(ErrNoCheckpointer)
+	}
+
+	checkpoint, err := goStepsCtx.checkpointer.Load(runID)
+	if err != nil {
+		return fail(err)
+	}
+
+	goStepsCtx.WithData(checkpoint.Data)
+	goStepsCtx.run.checkpoint = &checkpointRun{
+		checkpointer: *goStepsCtx.checkpointer,
+		checkpoint:   checkpoint,
+	}
+
+	return branch.executeRun(goStepsCtx)
+}
+
+// stepPosition returns the position of the step at the index, in the step-tree, example 1/divide/0
+func (ctx GoStepsCtx) stepPosition(index int) string {
+	if ctx.position == "" {
+		return strconv.Itoa(index)
+	}
+
+	return ctx.position + "/" + strconv.Itoa(index)
+}
+
+// completedStep returns the progress of the step at the position, if completed in the checkpoint
+func (run *checkpointRun) completedStep(position string) (StepProgress, bool) {
+	if run == nil {
+		return StepProgress{}, false
+	}
+
+	progress, ok := run.checkpoint.Completed[position]
+	return progress, ok
+}
+
+// selectedBranch returns the branch selected by the step at the position, if selected in the checkpoint
+func (run *checkpointRun) selectedBranch(position string) (BranchName, bool) {
+	if run == nil {
+		return "", false
+	}
+
+	branchName, ok := run.checkpoint.Branches[position]
+	return branchName, ok
+}
+
+// save saves the checkpoint after the step at the position is executed, with
+// the data of the context, the progress of the step and the branch selected
+func (run *checkpointRun) save(c *GoStepsCtx, position string, step *Step, branch *Branch) {
+	if run == nil {
+		return
+	}
+
+	checkpoint := run.checkpoint
+	if step.stepResult != nil && step.stepResult.isDone() {
+		progress := step.getProgress()
+		progress.StepResult.StepError = nil
+		checkpoint.Completed[position] = progress
+	}
+
+	if branch != nil {
+		checkpoint.Branches[position] = branch.BranchName
+	}
+
+	checkpoint.Data = c.Snapshot()
+	checkpoint.Position = position
+	checkpoint.UpdatedAt = time.Now()
+
+	if err := run.checkpointer.Save(checkpoint); err != nil {
+		c.Log(fmt.Sprintf("failed to save checkpoint of run [%s]: %v", checkpoint.RunID, err), ErrorLevel)
+	}
+}
+
+// reset resets the progress of the step at the position, and of the steps of its branches,
+// in the checkpoint, example of the steps run again by a goto, so that they are not restored
+func (run *checkpointRun) reset(position string) {
+	if run == nil {
+		return
+	}
+
+	for completed := range run.checkpoint.Completed {
+		if completed == position || strings.HasPrefix(completed, position+"/") {
+			delete(run.checkpoint.Completed, completed)
+		}
+	}
+
+	for selected := range run.checkpoint.Branches {
+		if selected == position || strings.HasPrefix(selected, position+"/") {
+			delete(run.checkpoint.Branches, selected)
+		}
+	}
+}
+
+// delete deletes the checkpoint of a completed run
+func (run *checkpointRun) delete(c *GoStepsCtx) {
+	if run == nil {
+		return
+	}
+
+	if err := run.checkpointer.Store.Delete(run.checkpoint.RunID); err != nil {
+		c.Log(fmt.Sprintf("failed to delete checkpoint of run [%s]: %v", run.checkpoint.RunID, err), ErrorLevel)
+	}
+}
+
+// restore restores the progress of a step completed in the checkpoint, without running the step
+func (step *Step) restore(c *GoStepsCtx, progress StepProgress) {
+	stepResult := progress.StepResult
+
+	step.resetProgress()
+	step.stepRunProgress.runCount = progress.RunCount
+	step.stepRunProgress.duration = progress.Duration
+	step.setResult(&stepResult)
+
+	c.setStepProgress(step)
+}
+
+// MemoryCheckpointStore type defines a checkpoint store in memory, safe for concurrent use
+type MemoryCheckpointStore struct {
+	mutex       sync.Mutex
+	checkpoints map[RunID][]byte
+}
+
+// NewMemoryCheckpointStore creates a new checkpoint store in memory
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{
+		checkpoints: map[RunID][]byte{},
+	}
+}
+
+// Save saves the checkpoint of the run
+func (store *MemoryCheckpointStore) Save(runID RunID, checkpoint []byte) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.checkpoints[runID] = append([]byte(nil), checkpoint...)
+	return nil
+}
+
+// Load loads the checkpoint of the run
+func (store *MemoryCheckpointStore) Load(runID RunID) ([]byte, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	checkpoint, ok := store.checkpoints[runID]
+	if !ok {
+		return nil, fmt.Errorf("%w: run [%s]", ErrCheckpointNotFound, runID)
+	}
+
+	return append([]byte(nil), checkpoint...), nil
+}
+
+// Delete deletes the checkpoint of the run
+func (store *MemoryCheckpointStore) Delete(runID RunID) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.checkpoints, runID)
+	return nil
+}
+
+// FileCheckpointStore type defines a checkpoint store in a directory,
+// with a file per run, named after the run id, example order-42.checkpoint
+type FileCheckpointStore struct {
+	Dir string
+}
+
+// NewFileCheckpointStore creates a new checkpoint store in the directory
+func NewFileCheckpointStore(dir string) *FileCheckpointStore {
+	return &FileCheckpointStore{
+		Dir: dir,
+	}
+}
+
+// path returns the path of the checkpoint file of the run
+func (store *FileCheckpointStore) path(runID RunID) (string, error) {
+	if runID == "" || strings.ContainsAny(string(runID), `/\`) || runID == "." || runID == ".." {
+		return "", fmt.Errorf("error: invalid run id [%s] for a checkpoint file", runID)
+	}
+
+	return filepath.Join(store.Dir, string(runID)+".checkpoint"), nil
+}
+
+// Save saves the checkpoint of the run, the file is replaced
+// atomically, so that a crash does not leave a partial checkpoint
+func (store *FileCheckpointStore) Save(runID RunID, checkpoint []byte) error {
+	path, err := store.path(runID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(store.Dir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(store.Dir, string(runID)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(checkpoint); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// Load loads the checkpoint of the run
+func (store *FileCheckpointStore) Load(runID RunID) ([]byte, error) {
+	path, err := store.path(runID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: run [%s]", ErrCheckpointNotFound, runID)
+	}
+
+	return checkpoint, err
+}
+
+// Delete deletes the checkpoint of the run, if any
+func (store *FileCheckpointStore) Delete(runID RunID) error {
+	path, err := store.path(runID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package gosteps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Resume(t *testing.T) {
+
+	testCases := []struct {
+		Name  string
+		Store CheckpointStore
+		Codec Codec
+	}{
+		{
+			Name:  "memory store, json codec",
+			Store: NewMemoryCheckpointStore(),
+		},
+		{
+			Name:  "file store, gob codec",
+			Store: NewFileCheckpointStore(t.TempDir()),
+			Codec: GobCodec{},
+		},
+	}
+
+	for _, tc := range testCases {
+		runs := map[StepName]int{}
+		resolves := 0
+		fail := true
+
+		steps := Steps{
+			{
+				Name: "step1",
+				Function: func(c GoStepsCtx) StepResult {
+					runs["step1"] += 1
+					return MarkStateComplete().WithData(map[string]interface{}{
+						"result": 2,
+						"nested": map[string]interface{}{"ratio": 0.5},
+					})
+				},
+				Branches: &Branches{
+					Resolver: func(c GoStepsCtx) BranchName {
+						resolves += 1
+						return "double"
+					},
+					Branches: []Branch{
+						{
+							BranchName: "double",
+							Steps: Steps{
+								{
+									Name: "step2",
+									Function: func(c GoStepsCtx) StepResult {
+										runs["step2"] += 1
+										if fail {
+											return MarkStateFailed()
+										}
+
+										return MarkStateComplete().WithData(map[string]interface{}{
+											"result": c.GetData("result").(int) * 2,
+										})
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		checkpointer := NewCheckpointer(tc.Store, tc.Codec)
+
+		ctx := NewGoStepsContext().Use(checkpointer, RunID("run-1"))
+		report := NewStepsProcessor(steps).Execute(ctx)
+
+		assert.Equal(t, StepStateFailed, report.Outcome, tc.Name)
+		assert.Equal(t, RunID("run-1"), report.RunID, tc.Name)
+
+		checkpoint, err := checkpointer.Load("run-1")
+		assert.NoError(t, err, tc.Name)
+		assert.Equal(t, "0/double/0", checkpoint.Position, tc.Name)
+		assert.Equal(t, BranchName("double"), checkpoint.Branches["0"], tc.Name)
+		assert.Equal(t, StepStateComplete, checkpoint.Completed["0"].StepResult.StepState, tc.Name)
+
+		// resume with a new context, as a new process would
+		fail = false
+		ctx = NewGoStepsContext().Use(checkpointer)
+		report = NewStepsProcessor(steps).Resume(ctx, "run-1")
+
+		assert.Equal(t, StepStateComplete, report.Outcome, tc.Name)
+		assert.NoError(t, report.Error, tc.Name)
+		assert.Equal(t, 4, ctx.GetData("result"), tc.Name)
+		assert.Equal(t, map[string]interface{}{"ratio": 0.5}, ctx.GetData("nested"), tc.Name)
+		assert.Equal(t, map[StepName]int{"step1": 1, "step2": 2}, runs, tc.Name)
+		assert.Equal(t, 1, resolves, tc.Name)
+		assert.Equal(t, []BranchSelection{{StepName: "step1", BranchName: "double"}}, report.BranchPath, tc.Name)
+		assert.Len(t, report.Steps, 2, tc.Name)
+
+		// the checkpoint of a completed run is deleted
+		_, err = checkpointer.Load("run-1")
+		assert.True(t, errors.Is(err, ErrCheckpointNotFound), tc.Name)
+	}
+}
+
+func Test_Resume_Errors(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	// the OnChainEnd hooks are called with the reports of the failed resumes
+	ended := []*ExecutionReport{}
+	hooks := Hooks{
+		OnChainEnd: func(c GoStepsCtx, report *ExecutionReport) {
+			ended = append(ended, report)
+		},
+	}
+
+	report := NewStepsProcessor(steps).Resume(NewGoStepsContext().Use(hooks), "run-1")
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.True(t, errors.Is(report.Error, ErrNoCheckpointer))
+
+	ctx := NewGoStepsContext().Use(NewCheckpointer(NewMemoryCheckpointStore(), nil), hooks)
+	report = NewStepsProcessor(steps).Resume(ctx, "run-1")
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.True(t, errors.Is(report.Error, ErrCheckpointNotFound))
+
+	assert.Len(t, ended, 2)
+	assert.True(t, errors.Is(ended[0].Error, ErrNoCheckpointer))
+	assert.True(t, errors.Is(ended[1].Error, ErrCheckpointNotFound))
+
+	// a run id is generated if not set
+	report = NewStepsProcessor(steps).Execute(ctx)
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.NotEmpty(t, report.RunID)
+
+	err := NewFileCheckpointStore(t.TempDir()).Save("../run-1", []byte("{}"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package gosteps
+
+import (
+	"context"
+	"time"
+)
+
+// Clock type defines how the executor sleeps between the attempts of the steps, set the
+// clock of a context with ctx.Use(clock), the real time is used if not set. Tests can
+// use a fake clock, example gostepstest.FakeClock, to make the retry sleeps instant
+type Clock interface {
+	// Now returns the current time of the clock
+	Now() time.Time
+	// Sleep sleeps for the duration, returns the error of the context if it is done before
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock type defines the Clock of the real time
+type realClock struct{}
+
+// Now returns the current time
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep sleeps for the duration, returns the error
+// of the context if it is done before the sleep is over
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// getClock returns the clock of the context, the real time if not set
+func (ctx GoStepsCtx) getClock() Clock {
+	if ctx.clock == nil {
+		return realClock{}
+	}
+
+	return ctx.clock
+}
@@ -0,0 +1,93 @@
+package gosteps
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingClock records the sleeps, without sleeping, the sleeps advance its time
+type recordingClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (clock *recordingClock) Now() time.Time {
+	return clock.now
+}
+
+func (clock *recordingClock) Sleep(ctx context.Context, d time.Duration) error {
+	clock.sleeps = append(clock.sleeps, d)
+	clock.now = clock.now.Add(d)
+	return ctx.Err()
+}
+
+func Test_Clock(t *testing.T) {
+
+	clock := &recordingClock{}
+
+	attempts := []int{}
+	steps := Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				attempts = append(attempts, c.Attempt())
+				return MarkStateError().WithError(error1)
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 3,
+				RetryAllErrors: true,
+				RetrySleep:     time.Hour,
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext().Use(clock))
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour}, clock.sleeps)
+}
+
+func Test_StepPath(t *testing.T) {
+
+	paths := []string{}
+	record := func(c GoStepsCtx) StepResult {
+		paths = append(paths, c.StepPath())
+		return MarkStateComplete()
+	}
+
+	steps := Steps{
+		{
+			Name:     "step1",
+			Function: record,
+			Branches: &Branches{
+				DefaultBranch: "divide",
+				Branches: []Branch{
+					{
+						BranchName: "divide",
+						Steps: Steps{
+							{Name: "step3.divide", Function: record},
+							{
+								Name: "step4.login",
+								SubWorkflow: &SubWorkflow{
+									Branch: &Branch{BranchName: "auth", Steps: Steps{{Name: "fetch", Function: record}}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{Name: "step2", Function: record},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, []string{"step1", "divide/step3.divide", "divide/step4.login/auth/fetch", "step2"}, paths)
+	assert.Equal(t, "", NewGoStepsContext().(*GoStepsCtx).StepPath())
+	assert.Equal(t, 0, NewGoStepsContext().(*GoStepsCtx).Attempt())
+}
@@ -0,0 +1,109 @@
+package gosteps
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// detachedContext type defines a context.Context with the values of its parent, that is
+// never done, compensations are run even if the step-chain was cancelled or timed out
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline returns no deadline
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, the context is never done
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, the context is never done
+func (detachedContext) Err() error {
+	return nil
+}
+
+// Value returns the value of the key from the parent context
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
+
+// addCompensation records a completed step with a compensation, to compensate if the step-chain fails
+func (run *goStepsRun) addCompensation(step *Step) {
+	if run == nil {
+		return
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.compensations = append(run.compensations, step)
+}
+
+// shouldCompensate checks if the step-chain was terminated by a failed step,
+// or by a step in error, after its retries, and should be compensated
+func (run *goStepsRun) shouldCompensate() bool {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	if run.terminatingStep == nil {
+		return false
+	}
+
+	switch run.terminatingStep.StepResult.StepState {
+	case StepStateFailed, StepStateError:
+		return true
+	default:
+		return false
+	}
+}
+
+// compensate runs the compensations of the completed steps in reverse order of completion,
+// if the step-chain failed. All the compensations are run, even if a compensation does not
+// complete, the compensations are run with a context.Context that is never done. Returns
+// true if any compensation was run
+func (c GoStepsCtx) compensate() bool {
+	if c.run == nil || !c.run.shouldCompensate() {
+		return false
+	}
+
+	c.run.mutex.Lock()
+	compensations := c.run.compensations
+	c.run.compensations = nil
+	c.run.mutex.Unlock()
+
+	c.ctx = detachedContext{parent: c.Context()}
+	for i := len(compensations) - 1; i >= 0; i-- {
+		progress := compensations[i].compensate(c)
+
+		c.run.mutex.Lock()
+		c.run.compensated = append(c.run.compensated, progress)
+		c.run.mutex.Unlock()
+
+		if !progress.StepResult.isDone() {
+			c.Log(fmt.Sprintf("compensation of step [%s] ended with state %s", progress.StepName, progress.StepResult.StepState), ErrorLevel)
+		}
+	}
+
+	return len(compensations) > 0
+}
+
+// compensate runs the compensation of the step once, and returns its progress
+func (step *Step) compensate(c GoStepsCtx) StepProgress {
+	c.SetCurrentStep(step.Name)
+
+	startedAt := time.Now()
+	stepResult := step.Compensate(c)
+
+	return StepProgress{
+		StepName:       c.namespaced(step.Name),
+		StepResult:     stepResult,
+		RunCount:       1,
+		MaxRunAttempts: 1,
+		Duration:       time.Since(startedAt),
+	}
+}
@@ -0,0 +1,143 @@
+package gosteps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Compensate(t *testing.T) {
+
+	compensated := []StepName{}
+	compensate := func(c GoStepsCtx) StepResult {
+		compensated = append(compensated, c.CurrentStep())
+		return MarkStateComplete()
+	}
+
+	complete := func(c GoStepsCtx) StepResult {
+		return MarkStateComplete()
+	}
+
+	testCases := []struct {
+		name                string
+		lastStep            StepFn
+		expectedOutcome     StepState
+		expectCompensations bool
+	}{
+		{
+			name:                "failed step-chain is compensated in reverse order",
+			lastStep:            func(c GoStepsCtx) StepResult { return MarkStateFailed().WithError(error1) },
+			expectedOutcome:     StepStateFailed,
+			expectCompensations: true,
+		},
+		{
+			name:                "step-chain in error is compensated",
+			lastStep:            func(c GoStepsCtx) StepResult { return MarkStateError().WithError(error1) },
+			expectedOutcome:     StepStateError,
+			expectCompensations: true,
+		},
+		{
+			name:                "cancelled step-chain is not compensated",
+			lastStep:            func(c GoStepsCtx) StepResult { return MarkStateCancelled() },
+			expectedOutcome:     StepStateCancelled,
+			expectCompensations: false,
+		},
+		{
+			name:                "completed step-chain is not compensated",
+			lastStep:            complete,
+			expectedOutcome:     StepStateComplete,
+			expectCompensations: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			compensated = []StepName{}
+
+			steps := Steps{
+				{Name: "create", Function: complete, Compensate: compensate},
+				{Name: "validate", Function: complete},
+				{
+					Name: "reserve",
+					Parallel: &Parallel{
+						Steps: Steps{
+							{Name: "reserve.stock", Function: complete, Compensate: compensate},
+							{Name: "reserve.skipped", Function: func(c GoStepsCtx) StepResult { return MarkStateSkipped() }, Compensate: compensate},
+						},
+					},
+					Compensate: compensate,
+					Branches: &Branches{
+						Resolver: func(ctx GoStepsCtx) BranchName { return "branch1" },
+						Branches: []Branch{
+							{
+								BranchName: "branch1",
+								Steps: Steps{
+									{Name: "branch1.step1", Function: complete, Compensate: compensate},
+									{Name: "branch1.step2", Function: tc.lastStep, Compensate: compensate},
+								},
+							},
+						},
+					},
+				},
+			}
+
+			report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+			assert.Equal(t, tc.expectedOutcome, report.Outcome)
+
+			// the completed steps of the parallel group are compensated after the group, skipped steps are not
+			expected := []StepName{}
+			if tc.expectCompensations {
+				expected = []StepName{"branch1.step1", "reserve", "reserve.stock", "create"}
+			}
+
+			assert.Equal(t, expected, compensated)
+			assert.Len(t, report.Compensations, len(expected))
+			assert.Nil(t, report.CompensationError)
+		})
+	}
+}
+
+func Test_Compensate_Errors(t *testing.T) {
+
+	compensationErr := errors.New("compensation error")
+
+	steps := Steps{
+		{
+			Name:     "step1",
+			Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() },
+			Compensate: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+		{
+			Name:     "step2",
+			Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() },
+			Compensate: func(c GoStepsCtx) StepResult {
+				assert.NoError(t, c.Context().Err())
+				return MarkStateError().WithError(compensationErr)
+			},
+		},
+		{
+			Name:     "step3",
+			Function: func(c GoStepsCtx) StepResult { return MarkStateFailed().WithError(error1) },
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.True(t, errors.Is(report.Error, error1))
+	assert.False(t, errors.Is(report.Error, compensationErr))
+
+	assert.Len(t, report.Compensations, 2)
+	assert.Equal(t, StepName("step2"), report.Compensations[0].StepName)
+	assert.Equal(t, StepStateError, report.Compensations[0].StepResult.StepState)
+	assert.Equal(t, StepName("step1"), report.Compensations[1].StepName)
+	assert.Equal(t, StepStateComplete, report.Compensations[1].StepResult.StepState)
+
+	var compensationError *CompensationError
+	assert.True(t, errors.As(report.CompensationError, &compensationError))
+	assert.Equal(t, StepName("step2"), compensationError.StepName)
+	assert.True(t, errors.Is(report.CompensationError, compensationErr))
+}
@@ -0,0 +1,50 @@
+package gosteps
+
+// skipReason checks the When and SkipIf conditions of the step, before the step is run,
+// returns the reason the step is skipped, and true if the step should be skipped
+func (step *Step) skipReason(c GoStepsCtx) (string, bool) {
+	if step.When == nil && step.SkipIf == nil {
+		return "", false
+	}
+
+	c.SetCurrentStep(step.Name)
+
+	if step.When != nil && !step.When(c) {
+		return "step skipped, When condition is false", true
+	}
+
+	if step.SkipIf != nil && step.SkipIf(c) {
+		return "step skipped, SkipIf condition is true", true
+	}
+
+	return "", false
+}
+
+// isGuarded checks if any of the branches has a When condition
+func (branches *Branches) isGuarded() bool {
+	for i := range branches.Branches {
+		if branches.Branches[i].When != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// guardedBranch returns the name of the first branch with a When condition that is true,
+// used to select the branch of branches without a resolver, empty if none is true
+func (branches *Branches) guardedBranch(c GoStepsCtx) BranchName {
+	for i := range branches.Branches {
+		branch := &branches.Branches[i]
+		if branch.When != nil && branch.When(c) {
+			return branch.BranchName
+		}
+	}
+
+	return ""
+}
+
+// allows checks if the When condition of the branch, if any, allows the branch to be executed
+func (branch *Branch) allows(c GoStepsCtx) bool {
+	return branch.When == nil || branch.When(c)
+}
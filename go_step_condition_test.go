@@ -0,0 +1,240 @@
+package gosteps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Step_Conditions(t *testing.T) {
+
+	isTrue := func(c GoStepsCtx) bool { return true }
+	isFalse := func(c GoStepsCtx) bool { return false }
+
+	testCases := []struct {
+		name            string
+		when            ConditionFn
+		skipIf          ConditionFn
+		expectedState   StepState
+		expectedMessage string
+	}{
+		{
+			name:          "no conditions",
+			expectedState: StepStateComplete,
+		},
+		{
+			name:          "when is true",
+			when:          isTrue,
+			expectedState: StepStateComplete,
+		},
+		{
+			name:            "when is false",
+			when:            isFalse,
+			expectedState:   StepStateSkipped,
+			expectedMessage: "step skipped, When condition is false",
+		},
+		{
+			name:          "skip if is false",
+			skipIf:        isFalse,
+			expectedState: StepStateComplete,
+		},
+		{
+			name:            "skip if is true",
+			skipIf:          isTrue,
+			expectedState:   StepStateSkipped,
+			expectedMessage: "step skipped, SkipIf condition is true",
+		},
+		{
+			name:            "when is true and skip if is true",
+			when:            isTrue,
+			skipIf:          isTrue,
+			expectedState:   StepStateSkipped,
+			expectedMessage: "step skipped, SkipIf condition is true",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			executed := []StepName{}
+			complete := func(c GoStepsCtx) StepResult {
+				executed = append(executed, c.CurrentStep())
+				return MarkStateComplete()
+			}
+
+			steps := Steps{
+				{
+					Name:     "step1",
+					Function: complete,
+					When:     tc.when,
+					SkipIf:   tc.skipIf,
+					Branches: &Branches{
+						Resolver: func(c GoStepsCtx) BranchName { return "branch1" },
+						Branches: []Branch{
+							{BranchName: "branch1", Steps: Steps{{Name: "branch1.step1", Function: complete}}},
+						},
+					},
+				},
+				{Name: "step2", Function: complete},
+			}
+
+			ended := []StepName{}
+			hooks := Hooks{
+				OnStepEnd: func(c GoStepsCtx, progress StepProgress) {
+					ended = append(ended, progress.StepName)
+				},
+			}
+
+			report := NewStepsProcessor(steps).Execute(NewGoStepsContext().Use(hooks))
+
+			assert.Equal(t, StepStateComplete, report.Outcome)
+			assert.Equal(t, tc.expectedState, report.Steps[0].StepResult.StepState)
+
+			// a skipped step is not run, its branches are not executed, and no hooks are called for it
+			if tc.expectedState == StepStateSkipped {
+				assert.Equal(t, tc.expectedMessage, *report.Steps[0].StepResult.StepMessage)
+				assert.Equal(t, 0, report.Steps[0].RunCount)
+				assert.Empty(t, report.BranchPath)
+				assert.Equal(t, []StepName{"step2"}, executed)
+				assert.Equal(t, []StepName{"step2"}, ended)
+			} else {
+				assert.Equal(t, []StepName{"step1", "branch1.step1", "step2"}, executed)
+			}
+		})
+	}
+}
+
+func Test_Branch_Guards(t *testing.T) {
+
+	amountKey := Key[int]("amount")
+	above := func(limit int) ConditionFn {
+		return func(c GoStepsCtx) bool { return amountKey.GetOr(c, 0) > limit }
+	}
+
+	testCases := []struct {
+		name           string
+		amount         int
+		resolver       ResolverFn
+		defaultBranch  BranchName
+		expectedBranch BranchName
+		expectedError  error
+	}{
+		{
+			name:           "first guard that is true is selected",
+			amount:         500,
+			expectedBranch: "review",
+		},
+		{
+			name:           "branch without guard is never selected without a resolver",
+			amount:         50,
+			expectedBranch: "",
+		},
+		{
+			name:           "default branch if no guard is true",
+			amount:         50,
+			defaultBranch:  "approve",
+			expectedBranch: "approve",
+		},
+		{
+			name:           "resolver selects a branch with a true guard",
+			amount:         2000,
+			resolver:       func(c GoStepsCtx) BranchName { return "escalate" },
+			expectedBranch: "escalate",
+		},
+		{
+			name:           "resolver selects a branch with a false guard",
+			amount:         500,
+			resolver:       func(c GoStepsCtx) BranchName { return "escalate" },
+			defaultBranch:  "approve",
+			expectedBranch: "approve",
+		},
+		{
+			name:          "default branch with a false guard is not selected",
+			amount:        5,
+			resolver:      func(c GoStepsCtx) BranchName { return "escalate" },
+			defaultBranch: "review",
+			expectedError: ErrUnresolvedBranch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			steps := Steps{
+				{
+					Name: "route",
+					Branches: &Branches{
+						Resolver:      tc.resolver,
+						DefaultBranch: tc.defaultBranch,
+						Branches: []Branch{
+							{BranchName: "review", When: above(100)},
+							{BranchName: "escalate", When: above(1000)},
+							{BranchName: "approve"},
+						},
+					},
+				},
+			}
+
+			root := NewStepsProcessor(steps)
+			assert.NoError(t, root.Validate())
+
+			c := NewGoStepsContext()
+			c.WithData(amountKey.Data(tc.amount))
+
+			report := root.Execute(c)
+
+			if tc.expectedError != nil {
+				assert.Equal(t, StepStateError, report.Outcome)
+				assert.True(t, errors.Is(report.Error, tc.expectedError))
+				return
+			}
+
+			assert.Equal(t, StepStateComplete, report.Outcome)
+			if tc.expectedBranch == "" {
+				assert.Empty(t, report.BranchPath)
+				return
+			}
+
+			assert.Equal(t, []BranchSelection{{StepName: "route", BranchName: tc.expectedBranch}}, report.BranchPath)
+		})
+	}
+}
+
+func Test_Plan_Conditions(t *testing.T) {
+
+	complete := func(c GoStepsCtx) StepResult {
+		return MarkStateComplete()
+	}
+
+	steps := Steps{
+		{
+			Name:     "step1",
+			Function: complete,
+			SkipIf:   func(c GoStepsCtx) bool { return true },
+			Branches: &Branches{
+				Resolver: func(c GoStepsCtx) BranchName { return "branch1" },
+				Branches: []Branch{{BranchName: "branch1"}},
+			},
+		},
+		{
+			Name:     "step2",
+			Function: complete,
+			Branches: &Branches{
+				Branches: []Branch{
+					{BranchName: "never", When: func(c GoStepsCtx) bool { return false }},
+					{BranchName: "always", When: func(c GoStepsCtx) bool { return true }},
+				},
+			},
+		},
+	}
+
+	plan := NewStepsProcessor(steps).Plan(NewGoStepsContext(), PlanOpts{})
+
+	assert.Equal(t, StepStateComplete, plan.Outcome)
+	assert.Equal(t, StepStateSkipped, plan.Steps[0].StepState)
+	assert.Equal(t, StepStateComplete, plan.Steps[1].StepState)
+	assert.Equal(t, []BranchSelection{{StepName: "step2", BranchName: "always"}}, plan.BranchPath)
+	assert.Equal(t, []UnreachableBranch{
+		{StepName: "step1", BranchName: "branch1", Reason: UnreachableNotReached},
+		{StepName: "step2", BranchName: "never", Reason: UnreachableNotSelected},
+	}, plan.Unreachable)
+}
@@ -1,20 +1,52 @@
 package gosteps
 
+import (
+	"context"
+	"errors"
+	"time"
+)
+
 // GoStepsCtxData type defines the data stored in the context
 type GoStepsCtxData map[string]interface{}
 
 // StepProgress type defines the progress of the step
 type StepProgress struct {
-	StepName   StepName   `json:"stepName"`
-	StepResult StepResult `json:"stepResult"`
+	StepName       StepName      `json:"stepName"`
+	StepResult     StepResult    `json:"stepResult"`
+	RunCount       int           `json:"runCount"`
+	MaxRunAttempts int           `json:"maxRunAttempts"`
+	Duration       time.Duration `json:"duration"`
+	WaitDuration   time.Duration `json:"waitDuration,omitempty"` // time waiting on the rate limiters, not included in the Duration
+	Iteration      int           `json:"iteration,omitempty"`    // iteration of the enclosing loop, 0 if not within a loop
 }
 
-// GoStepsCtx type defines the context for the step-chain
+// GoStepsCtx type defines the context for the step-chain, the data and progress of the
+// context are safe for concurrent use, and shared by the copies of the context passed to
+// the steps, resolvers and branches of the step-chain, steps of parallel groups and graphs
+// are run with a snapshot of the data, merged into the context once they are executed
 type GoStepsCtx struct {
-	data          GoStepsCtxData
-	currentStep   StepName
-	stepsProgress map[StepName]StepProgress
-	logger        *goStepsLogger
+	store        *ctxStore
+	currentStep  StepName
+	logger       *goStepsLogger
+	run          *goStepsRun
+	ctx          context.Context
+	parentCtx    context.Context
+	checkpointer *Checkpointer
+	runID        RunID
+	position     string
+	middlewares  []Middleware
+	hooks        []Hooks
+	step         *Step
+	attempt      int
+	stepBudget   StepBudget
+	budget       *stepBudget
+	flow         *controlFlow
+	scope        *stepScope
+	iteration    int
+	namespace    string
+	path         string
+	rateLimiters []RateLimiter
+	clock        Clock
 }
 
 // GoStepsContext interface defines the methods for the context
@@ -23,10 +55,16 @@ type GoStepsContext interface {
 	log(step *Step)
 
 	Use(args ...interface{}) GoStepsContext
+	Context() context.Context
 	Log(message string, levels ...LogLevel)
 	SetData(key string, value interface{})
 	GetData(key string) interface{}
+	CurrentStep() StepName
 	WithData(data map[string]interface{})
+	DeleteData(key string)
+	Snapshot() GoStepsCtxData
+	CompareAndSetData(key string, old, new interface{}) bool
+	UpdateData(key string, fn func(value interface{}) interface{}) interface{}
 	SetProgress(step StepName, stepResult StepResult) GoStepsCtx
 	SetCurrentStep(step StepName) GoStepsCtx
 }
@@ -38,9 +76,8 @@ func NewGoStepsContext() GoStepsContext {
 	})
 
 	return &GoStepsCtx{
-		data:          GoStepsCtxData{},
-		stepsProgress: map[StepName]StepProgress{},
-		logger:        &logger,
+		store:  newCtxStore(nil),
+		logger: &logger,
 	}
 }
 
@@ -49,12 +86,52 @@ func (ctx GoStepsCtx) getCtx() GoStepsCtx {
 	return ctx
 }
 
+// Context returns the context.Context of the step-chain execution, steps
+// can use it to listen for cancellation, example: <-ctx.Context().Done()
+func (ctx GoStepsCtx) Context() context.Context {
+	if ctx.ctx == nil {
+		return context.Background()
+	}
+
+	return ctx.ctx
+}
+
+// Done returns the done channel of the context.Context of the step-chain execution
+func (ctx GoStepsCtx) Done() <-chan struct{} {
+	return ctx.Context().Done()
+}
+
 // Handles adding handlers to the context
 func (ctx *GoStepsCtx) Use(args ...interface{}) GoStepsContext {
 	for i := range args {
 		switch arg := args[i].(type) {
 		case goStepsLogger:
 			ctx.logger = &arg
+		case Logger:
+			logger := newGoStepsLogger(arg, ctx.logger.config)
+			ctx.logger = &logger
+		case LoggerOpts:
+			logger := newGoStepsLogger(ctx.logger.logger, &arg)
+			ctx.logger = &logger
+		case *LoggerOpts:
+			logger := newGoStepsLogger(ctx.logger.logger, arg)
+			ctx.logger = &logger
+		case Checkpointer:
+			ctx.checkpointer = &arg
+		case RunID:
+			ctx.runID = arg
+		case Middleware:
+			ctx.middlewares = append(ctx.middlewares, arg)
+		case func(next StepFn) StepFn:
+			ctx.middlewares = append(ctx.middlewares, arg)
+		case Hooks:
+			ctx.hooks = append(ctx.hooks, arg)
+		case StepBudget:
+			ctx.stepBudget = arg
+		case RateLimiter:
+			ctx.rateLimiters = append(ctx.rateLimiters, arg)
+		case Clock:
+			ctx.clock = arg
 		}
 	}
 
@@ -63,34 +140,135 @@ func (ctx *GoStepsCtx) Use(args ...interface{}) GoStepsContext {
 
 // SetData sets the data in the context
 func (ctx GoStepsCtx) SetData(key string, value interface{}) {
-	ctx.data[key] = value
+	ctx.store.set(map[string]interface{}{key: value})
 }
 
 // GetData gets the data from the context
 func (ctx GoStepsCtx) GetData(key string) interface{} {
-	return ctx.data[key]
+	return ctx.store.get(key)
 }
 
-// WithData sets the data in the context
+// WithData sets the data in the context, all the keys are set at once
 func (ctx GoStepsCtx) WithData(data map[string]interface{}) {
-	for key, value := range data {
-		ctx.SetData(key, value)
+	if len(data) == 0 {
+		return
 	}
+
+	ctx.store.set(data)
+}
+
+// DeleteData deletes the data of the key from the context
+func (ctx GoStepsCtx) DeleteData(key string) {
+	ctx.store.delete(key)
+}
+
+// Snapshot returns a copy of the data of the context, changes
+// to the copy are not reflected in the context, and vice versa
+func (ctx GoStepsCtx) Snapshot() GoStepsCtxData {
+	return ctx.store.snapshot()
+}
+
+// CompareAndSetData sets the data of the key to the new value, if the data is the old
+// value, atomically, and returns true if the data was set. A nil old value matches a
+// missing key, values are compared with ==, or reflect.DeepEqual for maps and slices
+func (ctx GoStepsCtx) CompareAndSetData(key string, old, new interface{}) bool {
+	return ctx.store.compareAndSet(key, old, new)
+}
+
+// UpdateData sets the data of the key to the value returned by the function, called with
+// the current data of the key, nil if missing, atomically, and returns the value set. The
+// function must not read or write the data of the context, the data is locked while it runs
+func (ctx GoStepsCtx) UpdateData(key string, fn func(value interface{}) interface{}) interface{} {
+	return ctx.store.update(key, fn)
 }
 
 // SetProgress sets the progress of the step
 func (ctx *GoStepsCtx) SetProgress(step StepName, stepResult StepResult) GoStepsCtx {
-	ctx.stepsProgress[step] = StepProgress{
+	ctx.store.setProgress(StepProgress{
 		StepName:   step,
 		StepResult: stepResult,
-	}
+	})
 
 	return *ctx
 }
 
+// fork returns a copy of the context for a concurrent execution, with a
+// snapshot of the data and a separate progress, isolated from the context
+func (ctx GoStepsCtx) fork(c context.Context) GoStepsCtx {
+	ctx.store = newCtxStore(ctx.store.snapshot())
+	ctx.run = newGoStepsRun()
+	ctx.ctx = c
+
+	// the control results of the forked steps apply within the forked steps only
+	ctx.flow = &controlFlow{}
+	ctx.scope = nil
+
+	return ctx
+}
+
+// startRun starts a new run of a step-chain with the context, with
+// its own progress, control flow and budget of steps executed
+func (ctx *GoStepsCtx) startRun(c context.Context) {
+	ctx.ctx = c
+	ctx.parentCtx = c
+	ctx.run = newGoStepsRun()
+	ctx.flow = &controlFlow{}
+	ctx.budget = newStepBudget(ctx.stepBudget)
+}
+
+// merge merges the progress of a forked context run into the context
+func (ctx *GoStepsCtx) merge(run *goStepsRun) {
+	run.mutex.Lock()
+	ctx.store.setProgress(run.steps...)
+	run.mutex.Unlock()
+
+	ctx.run.merge(run)
+}
+
+// setStepProgress sets the progress of the step, with the run count and duration,
+// under the name of the step namespaced by the sub-workflow, if any
+func (ctx *GoStepsCtx) setStepProgress(step *Step) StepProgress {
+	progress := ctx.stepProgress(step)
+	ctx.store.setProgress(progress)
+
+	return progress
+}
+
 // GetProgress gets the progress of the step
 func (ctx GoStepsCtx) GetProgress(step StepName) StepProgress {
-	return ctx.stepsProgress[step]
+	return ctx.store.getProgress(step)
+}
+
+// CurrentStep returns the name of the step being executed
+func (ctx GoStepsCtx) CurrentStep() StepName {
+	return ctx.currentStep
+}
+
+// StepName returns the name of the step being executed, namespaced by the sub-workflow, if any,
+// the name of the step in the progress, the hooks and the report, example login/auth/fetch
+func (ctx GoStepsCtx) StepName() StepName {
+	return ctx.namespaced(ctx.currentStep)
+}
+
+// StepPath returns the path of the step being executed through the branches and sub-workflows
+// of the step-tree, example divide/step3.divide for the step [step3.divide] of the branch [divide]
+func (ctx GoStepsCtx) StepPath() string {
+	return ctx.path + string(ctx.currentStep)
+}
+
+// Attempt returns the attempt of the step being executed, from 1, 0 outside of a step function
+func (ctx GoStepsCtx) Attempt() int {
+	return ctx.attempt
+}
+
+// timedOut returns true if the error is the deadline of a timeout of the branch or step, and
+// not the deadline of the context.Context the step-chain is executed with
+func (ctx GoStepsCtx) timedOut(err error) bool {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	return ctx.parentCtx == nil || ctx.parentCtx.Err() == nil
 }
 
 // SetCurrentStep sets the current step
@@ -0,0 +1,139 @@
+package gosteps
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrMissingData is the error of a key without data in the context
+	ErrMissingData = errors.New("missing data")
+
+	// ErrDataType is the error of a key with data of another type in the context
+	ErrDataType = errors.New("data type mismatch")
+)
+
+// DataError type defines the error of reading the data of a key from the context,
+// the error is either ErrMissingData or ErrDataType, and can be matched using errors.Is
+type DataError struct {
+	StepName     StepName
+	Key          string
+	ExpectedType string
+	ActualType   string // type of the data in the context, empty if missing
+	Err          error
+}
+
+// Error returns the error message of the data error
+func (e *DataError) Error() string {
+	if errors.Is(e.Err, ErrMissingData) {
+		return fmt.Sprintf("error: data [%s] of step [%s] is missing, expected type [%s]", e.Key, e.StepName, e.ExpectedType)
+	}
+
+	return fmt.Sprintf("error: data [%s] of step [%s] is of type [%s], expected type [%s]", e.Key, e.StepName, e.ActualType, e.ExpectedType)
+}
+
+// Unwrap returns the underlying error of the data error
+func (e *DataError) Unwrap() error {
+	return e.Err
+}
+
+// DataReader interface defines a context from which data can be read,
+// both GoStepsCtx and GoStepsContext implement it
+type DataReader interface {
+	GetData(key string) interface{}
+	CurrentStep() StepName
+}
+
+// Get returns the data of the key from the context as type T, returns a *DataError
+// if the key has no data, or the data is not of type T. Values are not converted,
+// example numbers loaded from JSON or YAML are of type int or float64
+func Get[T any](ctx DataReader, key string) (T, error) {
+	var zero T
+
+	value := ctx.GetData(key)
+	if value == nil {
+		return zero, newDataError[T](ctx, key, value, ErrMissingData)
+	}
+
+	typed, ok := value.(T)
+	if !ok {
+		return zero, newDataError[T](ctx, key, value, ErrDataType)
+	}
+
+	return typed, nil
+}
+
+// MustGet returns the data of the key from the context as type T,
+// panics with a *DataError if the key has no data of type T
+func MustGet[T any](ctx DataReader, key string) T {
+	value, err := Get[T](ctx, key)
+	if err != nil {
+		panic(err)
+	}
+
+	return value
+}
+
+// GetOr returns the data of the key from the context as type T, or the
+// default value if the key has no data, or the data is not of type T
+func GetOr[T any](ctx DataReader, key string, defaultValue T) T {
+	value, err := Get[T](ctx, key)
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// newDataError returns the error of reading the data of the key as type T
+func newDataError[T any](ctx DataReader, key string, value interface{}, err error) *DataError {
+	dataError := &DataError{
+		StepName:     ctx.CurrentStep(),
+		Key:          key,
+		ExpectedType: reflect.TypeOf((*T)(nil)).Elem().String(),
+		Err:          err,
+	}
+
+	if value != nil {
+		dataError.ActualType = reflect.TypeOf(value).String()
+	}
+
+	return dataError
+}
+
+// Key type defines a typed key of the context data, declared once and shared
+// by the steps producing and consuming the data, example:
+//
+//	var ResultKey = gosteps.Key[int]("result")
+//
+//	return gosteps.MarkStateComplete().WithData(ResultKey.Data(4))
+//	result, err := ResultKey.Get(c)
+type Key[T any] string
+
+// Name returns the name of the key in the context data
+func (key Key[T]) Name() string {
+	return string(key)
+}
+
+// Get returns the data of the key from the context, see Get
+func (key Key[T]) Get(ctx DataReader) (T, error) {
+	return Get[T](ctx, string(key))
+}
+
+// MustGet returns the data of the key from the context, see MustGet
+func (key Key[T]) MustGet(ctx DataReader) T {
+	return MustGet[T](ctx, string(key))
+}
+
+// GetOr returns the data of the key from the context, or the default value, see GetOr
+func (key Key[T]) GetOr(ctx DataReader, defaultValue T) T {
+	return GetOr[T](ctx, string(key), defaultValue)
+}
+
+// Data returns the data with the value of the key, for StepResult.WithData or GoStepsCtx.WithData
+func (key Key[T]) Data(value T) GoStepsCtxData {
+	return GoStepsCtxData{
+		string(key): value,
+	}
+}
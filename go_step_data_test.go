@@ -0,0 +1,77 @@
+package gosteps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	resultKey = Key[int]("result")
+	labelKey  = Key[string]("label")
+	factorKey = Key[int]("factor")
+)
+
+func Test_Get(t *testing.T) {
+
+	ctx := NewGoStepsContext()
+	ctx.SetData("result", 4)
+	ctx.SetData("label", "four")
+	ctx.SetCurrentStep("step1")
+
+	result, err := Get[int](ctx, "result")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result)
+
+	_, err = Get[string](ctx, "result")
+	assert.True(t, errors.Is(err, ErrDataType))
+	assert.EqualError(t, err, "error: data [result] of step [step1] is of type [int], expected type [string]")
+
+	_, err = Get[int](ctx, "missing")
+	assert.True(t, errors.Is(err, ErrMissingData))
+	assert.EqualError(t, err, "error: data [missing] of step [step1] is missing, expected type [int]")
+
+	var dataError *DataError
+	assert.True(t, errors.As(err, &dataError))
+	assert.Equal(t, StepName("step1"), dataError.StepName)
+
+	assert.Equal(t, 7, GetOr(ctx, "missing", 7))
+	assert.Equal(t, "four", MustGet[string](ctx, "label"))
+	assert.Panics(t, func() { MustGet[float64](ctx, "result") })
+
+	stringer, err := Get[interface{ Error() string }](ctx, "label")
+	assert.Nil(t, stringer)
+	assert.EqualError(t, err, "error: data [label] of step [step1] is of type [string], expected type [interface { Error() string }]")
+}
+
+func Test_Key(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "produce",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete().WithData(resultKey.Data(4), labelKey.Data("four"))
+			},
+		},
+		{
+			Name: "consume",
+			Function: func(c GoStepsCtx) StepResult {
+				result, err := resultKey.Get(c)
+				if err != nil {
+					return MarkStateFailed().WithError(err)
+				}
+
+				return MarkStateComplete().WithData(resultKey.Data(result * factorKey.GetOr(c, 2)))
+			},
+		},
+	}
+
+	ctx := NewGoStepsContext()
+	report := NewStepsProcessor(steps).Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 8, resultKey.MustGet(ctx))
+	assert.Equal(t, "four", labelKey.MustGet(ctx))
+	assert.Equal(t, "result", resultKey.Name())
+}
@@ -0,0 +1,382 @@
+package gosteps
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diagramStart is the id of the start node of the diagrams
+	diagramStart = "chainStart"
+
+	// diagramEnd is the id of the end node of the diagrams
+	diagramEnd = "chainEnd"
+)
+
+// stateColors maps the StepState to the fill color of the nodes, in the diagrams with an execution report
+var stateColors = map[StepState]string{
+	StepStateComplete:  "#c8e6c9",
+	StepStateFailed:    "#ffcdd2",
+	StepStateSkipped:   "#eeeeee",
+	StepStatePending:   "#fff9c4",
+	StepStateError:     "#ef9a9a",
+	StepStateCancelled: "#ffe0b2",
+}
+
+// takenColor is the color of the edges of the path taken, in the diagrams with an execution report
+const takenColor = "#2e7d32"
+
+// diagramNode type defines a step of the step-tree, in the diagram
+type diagramNode struct {
+	id       string
+	name     StepName
+	lines    []string
+	state    StepState
+	executed bool
+}
+
+// diagramEdge type defines a transition between two nodes of the diagram
+type diagramEdge struct {
+	from  string
+	to    string
+	label string
+	taken bool
+}
+
+// diagramExit type defines a pending transition from a node, to the next node of the step-chain
+type diagramExit struct {
+	from   string
+	label  string
+	branch bool // the exit is labelled with the name of a branch of the step
+}
+
+// diagram type defines the nodes and edges of the step-tree, with the execution report, if any
+type diagram struct {
+	nodes    []diagramNode
+	edges    []diagramEdge
+	steps    map[StepName]StepProgress
+	branches map[BranchSelection]bool
+	report   *ExecutionReport
+}
+
+// ToMermaid returns the step-tree as a Mermaid flowchart, with the retry options of each step
+// and the branch names on the edges. If an execution report is provided, the nodes are colored
+// by the final state of the steps, and the path taken through the step-tree is highlighted
+func (branch *Branch) ToMermaid(report ...*ExecutionReport) string {
+	d := newDiagram(branch, report)
+
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	fmt.Fprintf(&sb, "    %s((start))\n", diagramStart)
+	fmt.Fprintf(&sb, "    %s((end))\n", diagramEnd)
+
+	for _, node := range d.nodes {
+		label := strings.ReplaceAll(strings.Join(node.lines, "<br/>"), `"`, "#quot;")
+		fmt.Fprintf(&sb, "    %s[\"%s\"]\n", node.id, label)
+	}
+
+	for _, edge := range d.edges {
+		if edge.label != "" {
+			// the quotes, and the | delimiting the label, are escaped as in the labels of the nodes
+			label := strings.NewReplacer(`"`, "#quot;", "|", "#124;").Replace(edge.label)
+			fmt.Fprintf(&sb, "    %s -->|%s| %s\n", edge.from, label, edge.to)
+		} else {
+			fmt.Fprintf(&sb, "    %s --> %s\n", edge.from, edge.to)
+		}
+	}
+
+	for _, node := range d.nodes {
+		if color, ok := stateColors[node.state]; ok {
+			fmt.Fprintf(&sb, "    style %s fill:%s\n", node.id, color)
+		}
+	}
+
+	for i, edge := range d.edges {
+		if edge.taken {
+			fmt.Fprintf(&sb, "    linkStyle %d stroke:%s,stroke-width:3px\n", i, takenColor)
+		}
+	}
+
+	return sb.String()
+}
+
+// ToDOT returns the step-tree as a Graphviz DOT digraph, see ToMermaid
+func (branch *Branch) ToDOT(report ...*ExecutionReport) string {
+	d := newDiagram(branch, report)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "digraph %q {\n", string(branch.BranchName))
+	sb.WriteString("    node [shape=box, style=rounded];\n")
+	fmt.Fprintf(&sb, "    %s [shape=circle, label=\"start\"];\n", diagramStart)
+	fmt.Fprintf(&sb, "    %s [shape=doublecircle, label=\"end\"];\n", diagramEnd)
+
+	// the backslashes, quotes and newlines of the labels are escaped, within the quotes of DOT
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	for _, node := range d.nodes {
+		label := strings.Join(escapeLines(escaper, node.lines), `\n`)
+		if color, ok := stateColors[node.state]; ok {
+			fmt.Fprintf(&sb, "    %s [label=\"%s\", style=\"rounded,filled\", fillcolor=\"%s\"];\n", node.id, label, color)
+		} else {
+			fmt.Fprintf(&sb, "    %s [label=\"%s\"];\n", node.id, label)
+		}
+	}
+
+	for _, edge := range d.edges {
+		attrs := []string{}
+		if edge.label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=\"%s\"", escaper.Replace(edge.label)))
+		}
+
+		if edge.taken {
+			attrs = append(attrs, fmt.Sprintf("color=%q", takenColor), "penwidth=3")
+		}
+
+		if len(attrs) > 0 {
+			fmt.Fprintf(&sb, "    %s -> %s [%s];\n", edge.from, edge.to, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&sb, "    %s -> %s;\n", edge.from, edge.to)
+		}
+	}
+
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
+
+// ToPlantUML returns the step-tree as a PlantUML state diagram, see ToMermaid
+func (branch *Branch) ToPlantUML(report ...*ExecutionReport) string {
+	d := newDiagram(branch, report)
+
+	var sb strings.Builder
+	sb.WriteString("@startuml\n")
+	sb.WriteString("hide empty description\n")
+
+	for _, node := range d.nodes {
+		label := strings.Join(escapeLines(strings.NewReplacer(`"`, "'", "\n", `\n`), node.lines), `\n`)
+		if color, ok := stateColors[node.state]; ok {
+			fmt.Fprintf(&sb, "state \"%s\" as %s %s\n", label, node.id, color)
+		} else {
+			fmt.Fprintf(&sb, "state \"%s\" as %s\n", label, node.id)
+		}
+	}
+
+	plantUMLNode := func(id string) string {
+		if id == diagramStart || id == diagramEnd {
+			return "[*]"
+		}
+
+		return id
+	}
+
+	for _, edge := range d.edges {
+		arrow := "-->"
+		if edge.taken {
+			arrow = fmt.Sprintf("-[%s,bold]->", takenColor)
+		}
+
+		fmt.Fprintf(&sb, "%s %s %s", plantUMLNode(edge.from), arrow, plantUMLNode(edge.to))
+		if edge.label != "" {
+			// the newlines are escaped as in the labels of the states, and the : separating the label too
+			label := strings.NewReplacer("\n", `\n`, ":", "&#58;").Replace(edge.label)
+			fmt.Fprintf(&sb, " : %s", label)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("@enduml\n")
+
+	return sb.String()
+}
+
+// newDiagram walks the step-tree of the branch, and builds its nodes and edges
+func newDiagram(branch *Branch, reports []*ExecutionReport) *diagram {
+	d := &diagram{
+		steps:    map[StepName]StepProgress{},
+		branches: map[BranchSelection]bool{},
+	}
+
+	for _, report := range reports {
+		if report != nil {
+			d.report = report
+		}
+	}
+
+	if d.report != nil {
+		for _, progress := range d.report.Steps {
+			d.steps[progress.StepName] = progress
+		}
+
+		for _, selection := range d.report.BranchPath {
+			d.branches[selection] = true
+		}
+	}
+
+	exits := d.walkSteps(branch.Steps, []diagramExit{{from: diagramStart}})
+	d.connect(exits, diagramEnd)
+
+	return d
+}
+
+// walkSteps adds the nodes of the steps, connected in sequence after the entries,
+// and returns the exits of the last step, to connect to the next step
+func (d *diagram) walkSteps(steps Steps, entries []diagramExit) []diagramExit {
+	for i := range steps {
+		entries = d.walkStep(&steps[i], entries)
+	}
+
+	return entries
+}
+
+// walkStep adds the node of the step, its parallel steps, loop and branches,
+// connected after the entries, and returns the exits of the step
+func (d *diagram) walkStep(step *Step, entries []diagramExit) []diagramExit {
+	node := diagramNode{
+		id:    fmt.Sprintf("step%d", len(d.nodes)),
+		name:  step.Name,
+		lines: append([]string{string(step.Name)}, step.StepOpts.diagramLines()...),
+	}
+
+	if progress, ok := d.steps[step.Name]; ok {
+		node.executed = true
+		node.state = progress.StepResult.StepState
+	}
+
+	for selection := range d.branches {
+		if selection.StepName == step.Name {
+			node.executed = true
+			break
+		}
+	}
+
+	if step.Parallel != nil {
+		node.lines[0] += " (parallel)"
+	}
+
+	if step.Loop != nil {
+		node.lines[0] += " (loop)"
+	}
+
+	// the steps of a sub-workflow are not drawn, the branch can be drawn on its own
+	if step.SubWorkflow != nil && step.SubWorkflow.Branch != nil {
+		node.lines[0] += fmt.Sprintf(" (sub-workflow %s)", step.SubWorkflow.Branch.BranchName)
+	}
+
+	d.nodes = append(d.nodes, node)
+	d.connect(entries, node.id)
+
+	exits := []diagramExit{{from: node.id}}
+
+	if step.Parallel != nil {
+		exits = []diagramExit{}
+		for i := range step.Parallel.Steps {
+			exits = append(exits, d.walkStep(&step.Parallel.Steps[i], []diagramExit{{from: node.id, label: "parallel"}})...)
+		}
+	}
+
+	// the steps of a loop return to the step running the loop, for the next iteration
+	if step.Loop != nil {
+		d.connect(d.walkSteps(step.Loop.Steps, []diagramExit{{from: node.id, label: "loop"}}), node.id)
+	}
+
+	if step.Branches != nil {
+		exits = []diagramExit{}
+		for i := range step.Branches.Branches {
+			branch := &step.Branches.Branches[i]
+			exits = append(exits, d.walkSteps(branch.Steps, []diagramExit{{from: node.id, label: string(branch.BranchName), branch: true}})...)
+		}
+	}
+
+	return exits
+}
+
+// connect adds the edges from the exits to the node, an edge is taken if both nodes were
+// executed, and the branch of the edge, if any, was selected in the execution report
+func (d *diagram) connect(exits []diagramExit, to string) {
+	for _, exit := range exits {
+		d.edges = append(d.edges, diagramEdge{
+			from:  exit.from,
+			to:    to,
+			label: exit.label,
+			taken: d.isTaken(exit, to),
+		})
+	}
+}
+
+// isTaken checks if the edge from the exit to the node was taken in the execution report
+func (d *diagram) isTaken(exit diagramExit, to string) bool {
+	if d.report == nil {
+		return false
+	}
+
+	from, ok := d.node(exit.from)
+	if exit.from != diagramStart && (!ok || !from.executed) {
+		return false
+	}
+
+	if to == diagramEnd {
+		if d.report.Outcome != StepStateComplete {
+			return false
+		}
+	} else if node, ok := d.node(to); !ok || !node.executed {
+		return false
+	}
+
+	if !exit.branch {
+		return true
+	}
+
+	// the exit of a branch is labelled with the branch name, from the node of the resolving step
+	return d.branches[BranchSelection{StepName: from.name, BranchName: BranchName(exit.label)}]
+}
+
+// node returns the node with the id
+func (d *diagram) node(id string) (diagramNode, bool) {
+	for _, node := range d.nodes {
+		if node.id == id {
+			return node, true
+		}
+	}
+
+	return diagramNode{}, false
+}
+
+// diagramLines returns the retry and timeout options of the step, as lines of the node label
+func (stepOpts StepOpts) diagramLines() []string {
+	lines := []string{}
+
+	if stepOpts.MaxRunAttempts > 1 {
+		lines = append(lines, fmt.Sprintf("max attempts: %d", stepOpts.MaxRunAttempts))
+
+		if stepOpts.Backoff != nil {
+			backoffType := "custom"
+			if spec := backoffToSpec(stepOpts.Backoff); spec != nil {
+				backoffType = spec.Type
+			}
+
+			lines = append(lines, "backoff: "+backoffType)
+		} else if stepOpts.RetrySleep > 0 {
+			lines = append(lines, fmt.Sprintf("retry sleep: %s", stepOpts.RetrySleep))
+		}
+	}
+
+	if stepOpts.Timeout > 0 {
+		lines = append(lines, fmt.Sprintf("timeout: %s", stepOpts.Timeout))
+	}
+
+	if stepOpts.TotalTimeout > 0 {
+		lines = append(lines, fmt.Sprintf("total timeout: %s", stepOpts.TotalTimeout))
+	}
+
+	return lines
+}
+
+// escapeLines returns the lines of a label, escaped by the replacer
+func escapeLines(replacer *strings.Replacer, lines []string) []string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = replacer.Replace(line)
+	}
+
+	return escaped
+}
@@ -0,0 +1,217 @@
+package gosteps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func diagramSteps() Steps {
+	return Steps{
+		{
+			Name: "fetch",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 3,
+				Backoff:        ExponentialBackoff{Initial: time.Second, Multiplier: 2},
+				Timeout:        5 * time.Second,
+			},
+			Branches: &Branches{
+				Resolver: func(ctx GoStepsCtx) BranchName {
+					return "fast"
+				},
+				Branches: []Branch{
+					{
+						BranchName: "fast",
+						Steps: Steps{
+							{
+								Name: "fast.process",
+								Function: func(c GoStepsCtx) StepResult {
+									return MarkStateComplete()
+								},
+							},
+						},
+					},
+					{
+						BranchName: "slow",
+						Steps: Steps{
+							{
+								Name: "slow.process",
+								Function: func(c GoStepsCtx) StepResult {
+									return MarkStateComplete()
+								},
+								StepOpts: StepOpts{
+									MaxRunAttempts: 2,
+									RetrySleep:     time.Second,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "store",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateError()
+			},
+		},
+	}
+}
+
+func Test_ToMermaid(t *testing.T) {
+
+	branch := NewStepsProcessor(diagramSteps())
+
+	expected := `flowchart TD
+    chainStart((start))
+    chainEnd((end))
+    step0["fetch<br/>max attempts: 3<br/>backoff: exponential<br/>timeout: 5s"]
+    step1["fast.process"]
+    step2["slow.process<br/>max attempts: 2<br/>retry sleep: 1s"]
+    step3["store"]
+    chainStart --> step0
+    step0 -->|fast| step1
+    step0 -->|slow| step2
+    step1 --> step3
+    step2 --> step3
+    step3 --> chainEnd
+`
+	assert.Equal(t, expected, branch.ToMermaid())
+
+	report := branch.Execute(NewGoStepsContext())
+
+	expected += `    style step0 fill:#c8e6c9
+    style step1 fill:#c8e6c9
+    style step3 fill:#ef9a9a
+    linkStyle 0 stroke:#2e7d32,stroke-width:3px
+    linkStyle 1 stroke:#2e7d32,stroke-width:3px
+    linkStyle 3 stroke:#2e7d32,stroke-width:3px
+`
+	assert.Equal(t, expected, branch.ToMermaid(report))
+}
+
+func Test_ToMermaid_EscapedLabels(t *testing.T) {
+
+	branch := NewStepsProcessor(Steps{
+		{
+			Name:     `say "hi"`,
+			Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() },
+			Branches: &Branches{
+				Branches: []Branch{
+					{BranchName: `quote "a"`},
+					{BranchName: "pipe|b"},
+				},
+			},
+		},
+	})
+
+	mermaid := branch.ToMermaid()
+
+	assert.Contains(t, mermaid, `step0["say #quot;hi#quot;"]`)
+	assert.Contains(t, mermaid, "step0 -->|quote #quot;a#quot;| chainEnd")
+	assert.Contains(t, mermaid, "step0 -->|pipe#124;b| chainEnd")
+}
+
+// awkwardSteps returns steps with names and branch names that must be escaped in the diagrams
+func awkwardSteps() Steps {
+	return Steps{
+		{
+			Name:     `path\`,
+			Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() },
+			Branches: &Branches{
+				Branches: []Branch{
+					{BranchName: "a: b"},
+					{BranchName: "line\nbreak"},
+					{BranchName: `quote "c"`},
+				},
+			},
+		},
+	}
+}
+
+func Test_ToDOT_EscapedLabels(t *testing.T) {
+
+	dot := NewStepsProcessor(awkwardSteps()).ToDOT()
+
+	assert.Contains(t, dot, `step0 [label="path\\"];`)
+	assert.Contains(t, dot, `step0 -> chainEnd [label="line\nbreak"];`)
+	assert.Contains(t, dot, `step0 -> chainEnd [label="quote \"c\""];`)
+}
+
+func Test_ToPlantUML_EscapedLabels(t *testing.T) {
+
+	plantUML := NewStepsProcessor(awkwardSteps()).ToPlantUML()
+
+	assert.Contains(t, plantUML, "step0 --> [*] : a&#58; b\n")
+	assert.Contains(t, plantUML, "step0 --> [*] : line\\nbreak\n")
+	assert.NotContains(t, plantUML, "line\nbreak")
+}
+
+func Test_ToDOT(t *testing.T) {
+
+	branch := NewStepsProcessor(diagramSteps())
+	report := branch.Execute(NewGoStepsContext())
+
+	expected := `digraph "root" {
+    node [shape=box, style=rounded];
+    chainStart [shape=circle, label="start"];
+    chainEnd [shape=doublecircle, label="end"];
+    step0 [label="fetch\nmax attempts: 3\nbackoff: exponential\ntimeout: 5s", style="rounded,filled", fillcolor="#c8e6c9"];
+    step1 [label="fast.process", style="rounded,filled", fillcolor="#c8e6c9"];
+    step2 [label="slow.process\nmax attempts: 2\nretry sleep: 1s"];
+    step3 [label="store", style="rounded,filled", fillcolor="#ef9a9a"];
+    chainStart -> step0 [color="#2e7d32", penwidth=3];
+    step0 -> step1 [label="fast", color="#2e7d32", penwidth=3];
+    step0 -> step2 [label="slow"];
+    step1 -> step3 [color="#2e7d32", penwidth=3];
+    step2 -> step3;
+    step3 -> chainEnd;
+}
+`
+	assert.Equal(t, expected, branch.ToDOT(report))
+}
+
+func Test_ToPlantUML(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "group",
+			Parallel: &Parallel{
+				Steps: Steps{
+					{
+						Name: "group.a",
+						Function: func(c GoStepsCtx) StepResult {
+							return MarkStateComplete()
+						},
+					},
+					{
+						Name: "group.b",
+						Function: func(c GoStepsCtx) StepResult {
+							return MarkStateComplete()
+						},
+					},
+				},
+			},
+		},
+	}
+
+	branch := NewStepsProcessor(steps)
+
+	expected := `@startuml
+hide empty description
+state "group (parallel)" as step0
+state "group.a" as step1
+state "group.b" as step2
+[*] --> step0
+step0 --> step1 : parallel
+step0 --> step2 : parallel
+step1 --> [*]
+step2 --> [*]
+@enduml
+`
+	assert.Equal(t, expected, branch.ToPlantUML())
+}
@@ -0,0 +1,230 @@
+package gosteps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrDuplicateStep is the error of a graph with more than one step with the same name
+	ErrDuplicateStep = errors.New("duplicate step")
+
+	// ErrUnknownDependency is the error of a graph step that depends on a step not in the graph
+	ErrUnknownDependency = errors.New("unknown dependency")
+
+	// ErrDependencyCycle is the error of a graph with steps that depend on each other
+	ErrDependencyCycle = errors.New("dependency cycle")
+)
+
+// Graph type defines a set of steps executed based on their dependencies, a step is executed
+// once all the steps in its Step.DependsOn complete, steps without pending dependencies
+// are executed concurrently. The step-graph stops scheduling steps once a step does not complete
+type Graph struct {
+	Steps          Steps `json:"steps"`
+	MaxConcurrency int   `json:"maxConcurrency"` // maximum steps run at once, unlimited if not set
+}
+
+// graphDependencies type defines the dependencies of the steps of a graph, built
+// for each execution, so that concurrent executions of the graph do not share them
+type graphDependencies struct {
+	dependencies []int   // number of dependencies of each step
+	dependents   [][]int // steps that depend on each step
+}
+
+// graphStepRun type defines the run of a step of the graph
+type graphStepRun struct {
+	index      int
+	run        *goStepsRun
+	store      *ctxStore
+	base       GoStepsCtxData
+	terminated bool
+}
+
+// NewGraph creates a new step-graph, returns an error if a step name is duplicated,
+// a step depends on an unknown step or the dependencies of the steps form a cycle
+func NewGraph(steps Steps) (*Graph, error) {
+	graph := &Graph{
+		Steps: steps,
+	}
+
+	if _, err := graph.build(); err != nil {
+		return nil, err
+	}
+
+	return graph, nil
+}
+
+// build returns the dependencies of the steps, and checks for cycles
+func (graph *Graph) build() (*graphDependencies, error) {
+	indexes := map[StepName]int{}
+	for i, step := range graph.Steps {
+		if _, ok := indexes[step.Name]; ok {
+			return nil, fmt.Errorf("%w: step [%s] is defined more than once", ErrDuplicateStep, step.Name)
+		}
+
+		indexes[step.Name] = i
+	}
+
+	deps := &graphDependencies{
+		dependencies: make([]int, len(graph.Steps)),
+		dependents:   make([][]int, len(graph.Steps)),
+	}
+	for i, step := range graph.Steps {
+		for _, dependency := range step.DependsOn {
+			j, ok := indexes[dependency]
+			if !ok {
+				return nil, fmt.Errorf("%w: step [%s] depends on unknown step [%s]", ErrUnknownDependency, step.Name, dependency)
+			}
+
+			deps.dependencies[i] += 1
+			deps.dependents[j] = append(deps.dependents[j], i)
+		}
+	}
+
+	// topological sort, the steps left unsorted are part of or depend on a cycle
+	dependencies := make([]int, len(graph.Steps))
+	copy(dependencies, deps.dependencies)
+
+	ready := graph.ready(dependencies)
+	sorted := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		sorted += 1
+
+		for _, j := range deps.dependents[i] {
+			dependencies[j] -= 1
+			if dependencies[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+
+	if sorted < len(graph.Steps) {
+		var cyclicSteps []string
+		for i, step := range graph.Steps {
+			if dependencies[i] > 0 {
+				cyclicSteps = append(cyclicSteps, string(step.Name))
+			}
+		}
+
+		return nil, fmt.Errorf("%w: between steps [%s]", ErrDependencyCycle, strings.Join(cyclicSteps, ", "))
+	}
+
+	return deps, nil
+}
+
+// ready returns the steps without pending dependencies, in order of definition
+func (graph *Graph) ready(dependencies []int) []int {
+	ready := []int{}
+	for i := range graph.Steps {
+		if dependencies[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	return ready
+}
+
+// Execute a step-graph with the context provided and
+// returns the report of the step-graph execution
+func (graph *Graph) Execute(c GoStepsContext) *ExecutionReport {
+	return graph.ExecuteContext(c.Context(), c)
+}
+
+// ExecuteContext executes a step-graph with the context provided, the step-graph
+// stops scheduling steps when the context.Context is done
+func (graph *Graph) ExecuteContext(ctx context.Context, c GoStepsContext) *ExecutionReport {
+	goStepsCtx := c.getCtx()
+	goStepsCtx.startRun(ctx)
+
+	deps, err := graph.build()
+	if err != nil {
+		report := goStepsCtx.run.report()
+		report.Outcome = StepStateFailed
+		report.Error = err
+
+		goStepsCtx.onChainEnd(report)
+		return report
+	}
+
+	// the steps are copied, so that the progress of the steps is isolated from the other executions of the graph
+	graph.execute(goStepsCtx, graph.Steps.clone(), deps)
+	goStepsCtx.compensate()
+
+	report := goStepsCtx.run.report()
+	goStepsCtx.onChainEnd(report)
+
+	return report
+}
+
+// execute schedules the steps, copies of the steps of the graph, each step is run with a forked
+// context, once a step is run its data and progress are merged into the context, and the steps
+// depending on it are scheduled
+func (graph *Graph) execute(c GoStepsCtx, steps Steps, deps *graphDependencies) {
+	concurrency := graph.MaxConcurrency
+	if concurrency <= 0 || concurrency > len(steps) {
+		concurrency = len(steps)
+	}
+
+	dependencies := make([]int, len(steps))
+	copy(dependencies, deps.dependencies)
+
+	ready := graph.ready(dependencies)
+	results := make(chan graphStepRun)
+	running := 0
+	terminated := false
+
+	for {
+		for !terminated && len(ready) > 0 && running < concurrency {
+			i := ready[0]
+			ready = ready[1:]
+			running += 1
+
+			stepCtx := c.fork(c.Context())
+			go func(i int, step Steps, stepCtx GoStepsCtx, base GoStepsCtxData) {
+				results <- graphStepRun{
+					index:      i,
+					run:        stepCtx.run,
+					store:      stepCtx.store,
+					base:       base,
+					terminated: step.execute(stepCtx),
+				}
+			}(i, steps[i:i+1], stepCtx, stepCtx.Snapshot())
+		}
+
+		if running == 0 {
+			return
+		}
+
+		result := <-results
+		running -= 1
+
+		c.merge(result.run)
+		for key, value := range result.run.changes(result.store, result.base) {
+			if _, ok := value.(deletedData); ok {
+				c.DeleteData(key)
+			} else {
+				c.SetData(key, value)
+			}
+		}
+
+		if result.terminated {
+			if !terminated {
+				c.run.terminate(*result.run.terminatingStep)
+			}
+
+			terminated = true
+			continue
+		}
+
+		for _, j := range deps.dependents[result.index] {
+			dependencies[j] -= 1
+			if dependencies[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+}
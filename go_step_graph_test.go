@@ -0,0 +1,211 @@
+package gosteps
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewGraph(t *testing.T) {
+
+	testCases := []struct {
+		Steps         Steps
+		ExpectedError error
+	}{
+		{
+			Steps: Steps{
+				{Name: "a"},
+				{Name: "b", DependsOn: []StepName{"a"}},
+				{Name: "c", DependsOn: []StepName{"a", "b"}},
+			},
+			ExpectedError: nil,
+		},
+		{
+			Steps: Steps{
+				{Name: "a"},
+				{Name: "a"},
+			},
+			ExpectedError: ErrDuplicateStep,
+		},
+		{
+			Steps: Steps{
+				{Name: "a", DependsOn: []StepName{"z"}},
+			},
+			ExpectedError: ErrUnknownDependency,
+		},
+		{
+			Steps: Steps{
+				{Name: "a"},
+				{Name: "b", DependsOn: []StepName{"a", "d"}},
+				{Name: "c", DependsOn: []StepName{"b"}},
+				{Name: "d", DependsOn: []StepName{"c"}},
+			},
+			ExpectedError: ErrDependencyCycle,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := NewGraph(tc.Steps)
+		if tc.ExpectedError == nil {
+			assert.NoError(t, err)
+			continue
+		}
+
+		assert.True(t, errors.Is(err, tc.ExpectedError))
+	}
+
+	_, err := NewGraph(testCases[3].Steps)
+	assert.EqualError(t, err, "dependency cycle: between steps [b, c, d]")
+}
+
+func Test_Graph_Execute(t *testing.T) {
+
+	sleepAndAdd := func(key string, dependencies ...string) StepFn {
+		return func(c GoStepsCtx) StepResult {
+			time.Sleep(20 * time.Millisecond)
+
+			sum := 1
+			for _, dependency := range dependencies {
+				sum += c.GetData(dependency).(int)
+			}
+
+			return MarkStateComplete().WithData(map[string]interface{}{
+				key: sum,
+			})
+		}
+	}
+
+	graph, err := NewGraph(Steps{
+		{Name: "d", Function: sleepAndAdd("d", "b", "c"), DependsOn: []StepName{"b", "c"}},
+		{Name: "b", Function: sleepAndAdd("b", "a"), DependsOn: []StepName{"a"}},
+		{Name: "c", Function: sleepAndAdd("c", "a"), DependsOn: []StepName{"a"}},
+		{Name: "a", Function: sleepAndAdd("a")},
+	})
+	assert.NoError(t, err)
+
+	ctx := NewGoStepsContext()
+	report := graph.Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 5, ctx.GetData("d"))
+	assert.Len(t, report.Steps, 4)
+	assert.Equal(t, StepName("a"), report.Steps[0].StepName)
+	assert.Equal(t, StepName("d"), report.Steps[3].StepName)
+	assert.Less(t, report.Duration, 80*time.Millisecond)
+	assert.Equal(t, StepStateComplete, ctx.getCtx().GetProgress("d").StepResult.StepState)
+}
+
+func Test_Graph_SetData(t *testing.T) {
+
+	graph, err := NewGraph(Steps{
+		{
+			Name: "a",
+			Function: func(c GoStepsCtx) StepResult {
+				c.SetData("a", 1)
+				c.DeleteData("initial")
+				return MarkStateComplete()
+			},
+		},
+		{
+			Name: "b",
+			Function: func(c GoStepsCtx) StepResult {
+				c.SetData("b", c.GetData("a").(int)+1)
+				return MarkStateComplete()
+			},
+			DependsOn: []StepName{"a"},
+		},
+	})
+	assert.NoError(t, err)
+
+	ctx := NewGoStepsContext()
+	ctx.SetData("initial", true)
+	report := graph.Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, GoStepsCtxData{"a": 1, "b": 2}, ctx.Snapshot())
+}
+
+func Test_Graph_ExecuteFailure(t *testing.T) {
+
+	ran := map[StepName]bool{}
+	step := func(name StepName, stepResult StepResult) Step {
+		return Step{
+			Name: name,
+			Function: func(c GoStepsCtx) StepResult {
+				ran[name] = true
+				return stepResult
+			},
+		}
+	}
+
+	a := step("a", MarkStateComplete())
+	b := step("b", MarkStateError().WithError(error1))
+	b.DependsOn = []StepName{"a"}
+	b.StepOpts = StepOpts{MaxRunAttempts: 2, RetryAllErrors: true}
+	c := step("c", MarkStateComplete())
+	c.DependsOn = []StepName{"b"}
+
+	graph, err := NewGraph(Steps{a, b, c})
+	assert.NoError(t, err)
+
+	report := graph.Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, StepName("b"), report.TerminatingStep.StepName)
+	assert.Equal(t, 2, report.TerminatingStep.RunCount)
+	assert.True(t, errors.Is(report.Error, error1))
+	assert.False(t, ran["c"])
+
+	report = (&Graph{Steps: Steps{{Name: "a", DependsOn: []StepName{"a"}}}}).Execute(NewGoStepsContext())
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.True(t, errors.Is(report.Error, ErrDependencyCycle))
+}
+
+func Test_Graph_ConcurrentExecute(t *testing.T) {
+
+	graph := &Graph{
+		Steps: Steps{
+			{Name: "a", Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() }},
+			{Name: "b", Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() }, DependsOn: []StepName{"a"}},
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			report := graph.Execute(NewGoStepsContext())
+			assert.Equal(t, StepStateComplete, report.Outcome)
+			assert.Len(t, report.Steps, 2)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func Test_Graph_BuildError_OnChainEnd(t *testing.T) {
+
+	graph := &Graph{
+		Steps: Steps{
+			{Name: "a", Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() }, DependsOn: []StepName{"unknown"}},
+		},
+	}
+
+	var ended *ExecutionReport
+	ctx := NewGoStepsContext().Use(Hooks{
+		OnChainEnd: func(c GoStepsCtx, report *ExecutionReport) {
+			ended = report
+		},
+	})
+
+	report := graph.Execute(ctx)
+
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.True(t, errors.Is(report.Error, ErrUnknownDependency))
+	assert.Same(t, report, ended)
+}
@@ -0,0 +1,693 @@
+package gosteps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format type defines the format of a step-tree definition
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Registry type maps names to the step functions, resolver functions, conditions, sub-workflows
+// and errors used by step-tree definitions, as functions and errors can not be serialized
+type Registry struct {
+	functions  map[string]StepFn
+	resolvers  map[string]ResolverFn
+	conditions map[string]ConditionFn
+	workflows  map[string]*Branch
+	errors     map[string]error
+}
+
+// NewRegistry returns a new registry, with the errors of gosteps registered
+func NewRegistry() *Registry {
+	registry := &Registry{
+		functions:  map[string]StepFn{},
+		resolvers:  map[string]ResolverFn{},
+		conditions: map[string]ConditionFn{},
+		workflows:  map[string]*Branch{},
+		errors:     map[string]error{},
+	}
+
+	return registry.RegisterErrors(ErrStepTimeout, ErrUnresolvedBranch, ErrDataConflict)
+}
+
+// RegisterFunction registers the step function with the name, a step refers to the function
+// with Step.FunctionName, or with its own name if Step.FunctionName is not set
+func (registry *Registry) RegisterFunction(name string, fn StepFn) *Registry {
+	registry.functions[name] = fn
+	return registry
+}
+
+// RegisterResolver registers the resolver function with the name,
+// branches refer to the resolver with Branches.ResolverName
+func (registry *Registry) RegisterResolver(name string, fn ResolverFn) *Registry {
+	registry.resolvers[name] = fn
+	return registry
+}
+
+// RegisterCondition registers the condition function with the name, loops refer to the condition
+// with Loop.WhileName, steps with Step.WhenName or Step.SkipIfName, branches with Branch.WhenName
+func (registry *Registry) RegisterCondition(name string, fn ConditionFn) *Registry {
+	registry.conditions[name] = fn
+	return registry
+}
+
+// RegisterWorkflow registers the branch with the name, sub-workflows refer to
+// the branch with SubWorkflow.WorkflowName, the branch is shared by reference
+func (registry *Registry) RegisterWorkflow(name string, branch *Branch) *Registry {
+	registry.workflows[name] = branch
+	return registry
+}
+
+// RegisterErrors registers the errors with their messages, the errors in
+// ErrorsToRetry and NeverRetryErrors are serialized as their messages
+func (registry *Registry) RegisterErrors(errs ...error) *Registry {
+	for _, err := range errs {
+		registry.errors[err.Error()] = err
+	}
+
+	return registry
+}
+
+// duration type defines a time.Duration serialized as a string, example "2s"
+type duration time.Duration
+
+// MarshalJSON marshals the duration as a string
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON unmarshals the duration from a string, or from a number of nanoseconds
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*d = duration(v)
+		return nil
+	case string:
+		return d.parse(v)
+	default:
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+}
+
+// MarshalYAML marshals the duration as a string
+func (d duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// UnmarshalYAML unmarshals the duration from a string, or from a number of nanoseconds
+func (d *duration) UnmarshalYAML(value *yaml.Node) error {
+	var nanoseconds int64
+	if err := value.Decode(&nanoseconds); err == nil {
+		*d = duration(nanoseconds)
+		return nil
+	}
+
+	return d.parse(value.Value)
+}
+
+// parse parses the duration from a string, example "2s"
+func (d *duration) parse(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = duration(parsed)
+	return nil
+}
+
+// branchSpec type defines the serializable definition of a Branch
+type branchSpec struct {
+	BranchName BranchName     `json:"branchName" yaml:"branchName"`
+	Steps      []stepSpec     `json:"steps" yaml:"steps"`
+	BranchOpts branchOptsSpec `json:"branchConfig" yaml:"branchConfig"`
+	WhenName   string         `json:"when,omitempty" yaml:"when,omitempty"`
+}
+
+// branchOptsSpec type defines the serializable definition of BranchOpts
+type branchOptsSpec struct {
+	Timeout               duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	ValidateBeforeExecute bool     `json:"validateBeforeExecute,omitempty" yaml:"validateBeforeExecute,omitempty"`
+}
+
+// stepSpec type defines the serializable definition of a Step
+type stepSpec struct {
+	Name         StepName               `json:"name" yaml:"name"`
+	FunctionName string                 `json:"function,omitempty" yaml:"function,omitempty"`
+	StepOpts     stepOptsSpec           `json:"stepConfig" yaml:"stepConfig"`
+	Branches     *branchesSpec          `json:"branches,omitempty" yaml:"branches,omitempty"`
+	Parallel     *parallelSpec          `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	Loop         *loopSpec              `json:"loop,omitempty" yaml:"loop,omitempty"`
+	SubWorkflow  *subWorkflowSpec       `json:"subWorkflow,omitempty" yaml:"subWorkflow,omitempty"`
+	WhenName     string                 `json:"when,omitempty" yaml:"when,omitempty"`
+	SkipIfName   string                 `json:"skipIf,omitempty" yaml:"skipIf,omitempty"`
+	DependsOn    []StepName             `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+	StepArgs     map[string]interface{} `json:"stepArgs,omitempty" yaml:"stepArgs,omitempty"`
+}
+
+// stepOptsSpec type defines the serializable definition of StepOpts, durations are
+// serialized as strings, error patterns as regular expressions and errors as messages
+type stepOptsSpec struct {
+	ErrorsToRetry        []string     `json:"errorsToRetry,omitempty" yaml:"errorsToRetry,omitempty"`
+	ErrorPatternsToRetry []string     `json:"errorPatternsToRetry,omitempty" yaml:"errorPatternsToRetry,omitempty"`
+	NeverRetryErrors     []string     `json:"neverRetryErrors,omitempty" yaml:"neverRetryErrors,omitempty"`
+	RetryAllErrors       bool         `json:"retryAllErrors,omitempty" yaml:"retryAllErrors,omitempty"`
+	MaxRunAttempts       int          `json:"maxAttempts,omitempty" yaml:"maxAttempts,omitempty"`
+	RetrySleep           duration     `json:"retrySleep,omitempty" yaml:"retrySleep,omitempty"`
+	Backoff              *backoffSpec `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	MaxRetrySleep        duration     `json:"maxRetrySleep,omitempty" yaml:"maxRetrySleep,omitempty"`
+	Timeout              duration     `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	TotalTimeout         duration     `json:"totalTimeout,omitempty" yaml:"totalTimeout,omitempty"`
+}
+
+// backoffSpec type defines the serializable definition of the built-in backoffs
+type backoffSpec struct {
+	Type       string   `json:"type" yaml:"type"` // constant, linear, exponential, exponentialJitter or decorrelatedJitter
+	Interval   duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Initial    duration `json:"initial,omitempty" yaml:"initial,omitempty"`
+	Increment  duration `json:"increment,omitempty" yaml:"increment,omitempty"`
+	Multiplier float64  `json:"multiplier,omitempty" yaml:"multiplier,omitempty"`
+	Base       duration `json:"base,omitempty" yaml:"base,omitempty"`
+}
+
+// branchesSpec type defines the serializable definition of Branches
+type branchesSpec struct {
+	Branches      []branchSpec           `json:"branches" yaml:"branches"`
+	ResolverName  string                 `json:"resolver,omitempty" yaml:"resolver,omitempty"`
+	DefaultBranch BranchName             `json:"defaultBranch,omitempty" yaml:"defaultBranch,omitempty"`
+	OnUnresolved  UnresolvedBranchPolicy `json:"onUnresolved,omitempty" yaml:"onUnresolved,omitempty"`
+}
+
+// parallelSpec type defines the serializable definition of Parallel
+type parallelSpec struct {
+	Steps          []stepSpec     `json:"steps" yaml:"steps"`
+	MaxConcurrency int            `json:"maxConcurrency,omitempty" yaml:"maxConcurrency,omitempty"`
+	FailFast       bool           `json:"failFast,omitempty" yaml:"failFast,omitempty"`
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty" yaml:"conflictPolicy,omitempty"`
+}
+
+// loopSpec type defines the serializable definition of Loop
+type loopSpec struct {
+	Steps         []stepSpec `json:"steps" yaml:"steps"`
+	WhileName     string     `json:"while,omitempty" yaml:"while,omitempty"`
+	MaxIterations int        `json:"maxIterations,omitempty" yaml:"maxIterations,omitempty"`
+}
+
+// subWorkflowSpec type defines the serializable definition of SubWorkflow
+type subWorkflowSpec struct {
+	WorkflowName string            `json:"workflow" yaml:"workflow"`
+	Inputs       map[string]string `json:"inputs,omitempty" yaml:"inputs,omitempty"`
+	Outputs      map[string]string `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+}
+
+// Marshal returns the step-tree definition in the JSON or YAML format, that can be loaded back
+// using LoadBranch, the functions, conditions and errors are written by their registered names
+func (branch *Branch) Marshal(format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.Marshal(branch.toSpec())
+	case FormatYAML:
+		return yaml.Marshal(branch.toSpec())
+	default:
+		return nil, fmt.Errorf("error: unknown step-tree format [%s]", format)
+	}
+}
+
+// LoadBranch loads a step-tree definition in the JSON or YAML format, as written by Branch.Marshal,
+// and rebuilds the branch with the functions and errors of the registry, unknown fields are errors
+func LoadBranch(r io.Reader, format Format, registry *Registry) (*Branch, error) {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+
+	var spec branchSpec
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(r)
+		decoder.UseNumber()
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("error: failed to decode json step-tree: %w", err)
+		}
+	case FormatYAML:
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&spec); err != nil {
+			return nil, fmt.Errorf("error: failed to decode yaml step-tree: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("error: unknown step-tree format [%s]", format)
+	}
+
+	return spec.toBranch(registry)
+}
+
+// LoadBranchFromBytes loads a step-tree definition in the JSON or YAML format, see LoadBranch
+func LoadBranchFromBytes(data []byte, format Format, registry *Registry) (*Branch, error) {
+	return LoadBranch(bytes.NewReader(data), format, registry)
+}
+
+// toSpec converts the branch to its serializable definition
+func (branch *Branch) toSpec() branchSpec {
+	return branchSpec{
+		BranchName: branch.BranchName,
+		Steps:      branch.Steps.toSpec(),
+		BranchOpts: branchOptsSpec{
+			Timeout:               duration(branch.BranchOpts.Timeout),
+			ValidateBeforeExecute: branch.BranchOpts.ValidateBeforeExecute,
+		},
+		WhenName: branch.WhenName,
+	}
+}
+
+// toSpec converts the steps to their serializable definitions
+func (steps Steps) toSpec() []stepSpec {
+	specs := make([]stepSpec, len(steps))
+	for i, step := range steps {
+		specs[i] = step.toSpec()
+	}
+
+	return specs
+}
+
+// toSpec converts the step to its serializable definition
+func (step Step) toSpec() stepSpec {
+	spec := stepSpec{
+		Name:         step.Name,
+		FunctionName: step.FunctionName,
+		StepOpts:     step.StepOpts.toSpec(),
+		WhenName:     step.WhenName,
+		SkipIfName:   step.SkipIfName,
+		DependsOn:    step.DependsOn,
+		StepArgs:     step.StepArgs,
+	}
+
+	if step.Branches != nil {
+		spec.Branches = &branchesSpec{
+			Branches:      make([]branchSpec, len(step.Branches.Branches)),
+			ResolverName:  step.Branches.ResolverName,
+			DefaultBranch: step.Branches.DefaultBranch,
+			OnUnresolved:  step.Branches.OnUnresolved,
+		}
+
+		for i := range step.Branches.Branches {
+			spec.Branches.Branches[i] = step.Branches.Branches[i].toSpec()
+		}
+	}
+
+	if step.Parallel != nil {
+		spec.Parallel = &parallelSpec{
+			Steps:          step.Parallel.Steps.toSpec(),
+			MaxConcurrency: step.Parallel.MaxConcurrency,
+			FailFast:       step.Parallel.FailFast,
+			ConflictPolicy: step.Parallel.ConflictPolicy,
+		}
+	}
+
+	if step.Loop != nil {
+		spec.Loop = &loopSpec{
+			Steps:         step.Loop.Steps.toSpec(),
+			WhileName:     step.Loop.WhileName,
+			MaxIterations: step.Loop.MaxIterations,
+		}
+	}
+
+	if step.SubWorkflow != nil {
+		spec.SubWorkflow = &subWorkflowSpec{
+			WorkflowName: step.SubWorkflow.WorkflowName,
+			Inputs:       step.SubWorkflow.Inputs,
+			Outputs:      step.SubWorkflow.Outputs,
+		}
+	}
+
+	return spec
+}
+
+// toSpec converts the step options to their serializable definition,
+// custom backoffs, RetryIf and RetryOnErrorTypes can not be serialized
+func (stepOpts StepOpts) toSpec() stepOptsSpec {
+	spec := stepOptsSpec{
+		ErrorsToRetry:    errorMessages(stepOpts.ErrorsToRetry),
+		NeverRetryErrors: errorMessages(stepOpts.NeverRetryErrors),
+		RetryAllErrors:   stepOpts.RetryAllErrors,
+		MaxRunAttempts:   stepOpts.MaxRunAttempts,
+		RetrySleep:       duration(stepOpts.RetrySleep),
+		Backoff:          backoffToSpec(stepOpts.Backoff),
+		MaxRetrySleep:    duration(stepOpts.MaxRetrySleep),
+		Timeout:          duration(stepOpts.Timeout),
+		TotalTimeout:     duration(stepOpts.TotalTimeout),
+	}
+
+	for _, re := range stepOpts.ErrorPatternsToRetry {
+		spec.ErrorPatternsToRetry = append(spec.ErrorPatternsToRetry, re.String())
+	}
+
+	return spec
+}
+
+// errorMessages returns the messages of the errors
+func errorMessages(errs []error) []string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+
+	return messages
+}
+
+// backoffToSpec converts the built-in backoffs to their serializable definition
+func backoffToSpec(backoff Backoff) *backoffSpec {
+	switch b := backoff.(type) {
+	case ConstantBackoff:
+		return &backoffSpec{Type: "constant", Interval: duration(b.Interval)}
+	case LinearBackoff:
+		return &backoffSpec{Type: "linear", Initial: duration(b.Initial), Increment: duration(b.Increment)}
+	case ExponentialBackoff:
+		return &backoffSpec{Type: "exponential", Initial: duration(b.Initial), Multiplier: b.Multiplier}
+	case ExponentialJitterBackoff:
+		return &backoffSpec{Type: "exponentialJitter", Initial: duration(b.Initial), Multiplier: b.Multiplier}
+	case DecorrelatedJitterBackoff:
+		return &backoffSpec{Type: "decorrelatedJitter", Base: duration(b.Base)}
+	default:
+		return nil
+	}
+}
+
+// toBranch rebuilds the branch from its definition, the When
+// condition is looked up in the registry by its name
+func (spec branchSpec) toBranch(registry *Registry) (*Branch, error) {
+	steps, err := stepsFromSpec(spec.Steps, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	when, err := registry.lookupCondition(spec.WhenName, fmt.Sprintf("branch [%s]", spec.BranchName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Branch{
+		BranchName: spec.BranchName,
+		Steps:      steps,
+		BranchOpts: BranchOpts{
+			Timeout:               time.Duration(spec.BranchOpts.Timeout),
+			ValidateBeforeExecute: spec.BranchOpts.ValidateBeforeExecute,
+		},
+		When:     when,
+		WhenName: spec.WhenName,
+	}, nil
+}
+
+// stepsFromSpec rebuilds the steps from their definitions
+func stepsFromSpec(specs []stepSpec, registry *Registry) (Steps, error) {
+	if specs == nil {
+		return nil, nil
+	}
+
+	steps := make(Steps, len(specs))
+	for i, spec := range specs {
+		step, err := spec.toStep(registry)
+		if err != nil {
+			return nil, err
+		}
+
+		steps[i] = step
+	}
+
+	return steps, nil
+}
+
+// toStep rebuilds the step from its definition, the function of the step is looked up in the
+// registry by the function name, or by the step name if the function name is not set, and the
+// When and SkipIf conditions and the branch of the sub-workflow of the step by their names
+func (spec stepSpec) toStep(registry *Registry) (Step, error) {
+	step := Step{
+		Name:         spec.Name,
+		FunctionName: spec.FunctionName,
+		WhenName:     spec.WhenName,
+		SkipIfName:   spec.SkipIfName,
+		DependsOn:    spec.DependsOn,
+	}
+
+	var err error
+	if step.When, err = registry.lookupCondition(spec.WhenName, fmt.Sprintf("step [%s]", spec.Name)); err != nil {
+		return Step{}, err
+	}
+
+	if step.SkipIf, err = registry.lookupCondition(spec.SkipIfName, fmt.Sprintf("step [%s]", spec.Name)); err != nil {
+		return Step{}, err
+	}
+
+	if spec.StepArgs != nil {
+		step.StepArgs = normalizeNumbers(spec.StepArgs).(map[string]interface{})
+	}
+
+	if spec.FunctionName != "" {
+		fn, ok := registry.functions[spec.FunctionName]
+		if !ok {
+			return Step{}, fmt.Errorf("error: function [%s] of step [%s] is not registered", spec.FunctionName, spec.Name)
+		}
+
+		step.Function = fn
+	} else if fn, ok := registry.functions[string(spec.Name)]; ok {
+		step.Function = fn
+	}
+
+	stepOpts, err := spec.StepOpts.toStepOpts(registry)
+	if err != nil {
+		return Step{}, fmt.Errorf("error: invalid stepConfig of step [%s]: %w", spec.Name, err)
+	}
+	step.StepOpts = stepOpts
+
+	if spec.Branches != nil {
+		branches, err := spec.Branches.toBranches(registry, spec.Name)
+		if err != nil {
+			return Step{}, err
+		}
+
+		step.Branches = branches
+	}
+
+	if spec.Parallel != nil {
+		steps, err := stepsFromSpec(spec.Parallel.Steps, registry)
+		if err != nil {
+			return Step{}, err
+		}
+
+		step.Parallel = &Parallel{
+			Steps:          steps,
+			MaxConcurrency: spec.Parallel.MaxConcurrency,
+			FailFast:       spec.Parallel.FailFast,
+			ConflictPolicy: spec.Parallel.ConflictPolicy,
+		}
+	}
+
+	if spec.Loop != nil {
+		loop, err := spec.Loop.toLoop(registry, spec.Name)
+		if err != nil {
+			return Step{}, err
+		}
+
+		step.Loop = loop
+	}
+
+	if spec.SubWorkflow != nil {
+		branch, ok := registry.workflows[spec.SubWorkflow.WorkflowName]
+		if !ok {
+			return Step{}, fmt.Errorf("error: workflow [%s] of step [%s] is not registered", spec.SubWorkflow.WorkflowName, spec.Name)
+		}
+
+		step.SubWorkflow = &SubWorkflow{
+			Branch:       branch,
+			WorkflowName: spec.SubWorkflow.WorkflowName,
+			Inputs:       spec.SubWorkflow.Inputs,
+			Outputs:      spec.SubWorkflow.Outputs,
+		}
+	}
+
+	return step, nil
+}
+
+// toLoop rebuilds the loop from its definition, the While
+// condition is looked up in the registry by its name
+func (spec loopSpec) toLoop(registry *Registry, stepName StepName) (*Loop, error) {
+	steps, err := stepsFromSpec(spec.Steps, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	while, err := registry.lookupCondition(spec.WhileName, fmt.Sprintf("step [%s]", stepName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Loop{
+		Steps:         steps,
+		While:         while,
+		WhileName:     spec.WhileName,
+		MaxIterations: spec.MaxIterations,
+	}, nil
+}
+
+// lookupCondition returns the condition registered with the name, nil if the name is not set,
+// the owner, example "step [name]", is the step or branch of the condition, used in the error
+func (registry *Registry) lookupCondition(name string, owner string) (ConditionFn, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	condition, ok := registry.conditions[name]
+	if !ok {
+		return nil, fmt.Errorf("error: condition [%s] of %s is not registered", name, owner)
+	}
+
+	return condition, nil
+}
+
+// toBranches rebuilds the branches from their definition, the resolver
+// of the branches is looked up in the registry by the resolver name
+func (spec branchesSpec) toBranches(registry *Registry, stepName StepName) (*Branches, error) {
+	branches := &Branches{
+		Branches:      make([]Branch, len(spec.Branches)),
+		ResolverName:  spec.ResolverName,
+		DefaultBranch: spec.DefaultBranch,
+		OnUnresolved:  spec.OnUnresolved,
+	}
+
+	if spec.ResolverName != "" {
+		resolver, ok := registry.resolvers[spec.ResolverName]
+		if !ok {
+			return nil, fmt.Errorf("error: resolver [%s] of step [%s] is not registered", spec.ResolverName, stepName)
+		}
+
+		branches.Resolver = resolver
+	}
+
+	for i, branchSpec := range spec.Branches {
+		branch, err := branchSpec.toBranch(registry)
+		if err != nil {
+			return nil, err
+		}
+
+		branches.Branches[i] = *branch
+	}
+
+	return branches, nil
+}
+
+// toStepOpts rebuilds the step options from their definition
+func (spec stepOptsSpec) toStepOpts(registry *Registry) (StepOpts, error) {
+	stepOpts := StepOpts{
+		RetryAllErrors: spec.RetryAllErrors,
+		MaxRunAttempts: spec.MaxRunAttempts,
+		RetrySleep:     time.Duration(spec.RetrySleep),
+		MaxRetrySleep:  time.Duration(spec.MaxRetrySleep),
+		Timeout:        time.Duration(spec.Timeout),
+		TotalTimeout:   time.Duration(spec.TotalTimeout),
+	}
+
+	var err error
+	if stepOpts.ErrorsToRetry, err = registry.lookupErrors(spec.ErrorsToRetry); err != nil {
+		return StepOpts{}, err
+	}
+
+	if stepOpts.NeverRetryErrors, err = registry.lookupErrors(spec.NeverRetryErrors); err != nil {
+		return StepOpts{}, err
+	}
+
+	for _, pattern := range spec.ErrorPatternsToRetry {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return StepOpts{}, err
+		}
+
+		stepOpts.ErrorPatternsToRetry = append(stepOpts.ErrorPatternsToRetry, *re)
+	}
+
+	if spec.Backoff != nil {
+		if stepOpts.Backoff, err = spec.Backoff.toBackoff(); err != nil {
+			return StepOpts{}, err
+		}
+	}
+
+	return stepOpts, nil
+}
+
+// lookupErrors looks up the registered errors by their messages
+func (registry *Registry) lookupErrors(messages []string) ([]error, error) {
+	var errs []error
+	for _, message := range messages {
+		err, ok := registry.errors[message]
+		if !ok {
+			return nil, fmt.Errorf("error [%s] is not registered", message)
+		}
+
+		errs = append(errs, err)
+	}
+
+	return errs, nil
+}
+
+// toBackoff rebuilds the built-in backoff from its definition
+func (spec backoffSpec) toBackoff() (Backoff, error) {
+	switch spec.Type {
+	case "constant":
+		return ConstantBackoff{Interval: time.Duration(spec.Interval)}, nil
+	case "linear":
+		return LinearBackoff{Initial: time.Duration(spec.Initial), Increment: time.Duration(spec.Increment)}, nil
+	case "exponential":
+		return ExponentialBackoff{Initial: time.Duration(spec.Initial), Multiplier: spec.Multiplier}, nil
+	case "exponentialJitter":
+		return ExponentialJitterBackoff{Initial: time.Duration(spec.Initial), Multiplier: spec.Multiplier}, nil
+	case "decorrelatedJitter":
+		return DecorrelatedJitterBackoff{Base: time.Duration(spec.Base)}, nil
+	default:
+		return nil, fmt.Errorf("unknown backoff type [%s]", spec.Type)
+	}
+}
+
+// normalizeNumbers converts the json.Number values decoded from JSON to int,
+// if they are integers, else to float64, so that JSON and YAML values match
+func normalizeNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil && i >= math.MinInt && i <= math.MaxInt {
+			return int(i)
+		}
+
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,311 @@
+package gosteps
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRegistry() *Registry {
+	return NewRegistry().
+		RegisterFunction("add", func(c GoStepsCtx) StepResult {
+			return MarkStateComplete().WithData(map[string]interface{}{
+				"result": c.GetData("n1").(int) + c.GetData("n2").(int),
+			})
+		}).
+		RegisterFunction("double", func(c GoStepsCtx) StepResult {
+			return MarkStateComplete().WithData(map[string]interface{}{
+				"result": c.GetData("result").(int) * 2,
+			})
+		}).
+		RegisterResolver("isEven", func(c GoStepsCtx) BranchName {
+			if c.GetData("result").(int)%2 == 0 {
+				return "even"
+			}
+			return "odd"
+		}).
+		RegisterCondition("belowLimit", func(c GoStepsCtx) bool {
+			return c.GetData("result").(int) < 100
+		}).
+		RegisterErrors(error1)
+}
+
+func Test_LoadBranch_RoundTrip(t *testing.T) {
+
+	registry := newTestRegistry()
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name:         "sum",
+			FunctionName: "add",
+			StepArgs: map[string]interface{}{
+				"n1": 5,
+				"n2": 4,
+				"nested": map[string]interface{}{
+					"ratio": 0.5,
+					"list":  []interface{}{1, "two"},
+				},
+			},
+			StepOpts: StepOpts{
+				ErrorsToRetry:        []error{error1, ErrStepTimeout},
+				ErrorPatternsToRetry: []regexp.Regexp{*regexp.MustCompile("err*")},
+				MaxRunAttempts:       3,
+				RetrySleep:           2 * time.Second,
+				Backoff:              ExponentialBackoff{Initial: time.Second, Multiplier: 3},
+				MaxRetrySleep:        time.Minute,
+				Timeout:              1500 * time.Millisecond,
+			},
+			Branches: &Branches{
+				ResolverName:  "isEven",
+				DefaultBranch: "even",
+				OnUnresolved:  OnUnresolvedFail,
+				Branches: []Branch{
+					{
+						BranchName: "odd",
+						Steps: Steps{
+							{Name: "double"},
+						},
+					},
+					{
+						BranchName: "even",
+					},
+				},
+			},
+		},
+		{
+			Name:       "group",
+			SkipIfName: "belowLimit",
+			Parallel: &Parallel{
+				FailFast: true,
+				Steps: Steps{
+					{Name: "double.parallel", FunctionName: "double", DependsOn: []StepName{"sum"}},
+				},
+			},
+		},
+		{
+			Name: "repeat",
+			Loop: &Loop{
+				WhileName:     "belowLimit",
+				MaxIterations: 3,
+				Steps: Steps{
+					{Name: "double.loop", FunctionName: "double"},
+				},
+			},
+		},
+	})
+	root.BranchOpts.Timeout = time.Hour
+
+	for _, format := range []Format{FormatJSON, FormatYAML} {
+		definition, err := root.Marshal(format)
+		assert.NoError(t, err)
+
+		loaded, err := LoadBranchFromBytes(definition, format, registry)
+		assert.NoError(t, err)
+
+		step := loaded.Steps[0]
+		assert.Equal(t, root.Steps[0].StepArgs, step.StepArgs)
+		assert.Equal(t, root.Steps[0].StepOpts.ErrorsToRetry, step.StepOpts.ErrorsToRetry)
+		assert.Equal(t, "err*", step.StepOpts.ErrorPatternsToRetry[0].String())
+		assert.Equal(t, root.Steps[0].StepOpts.Backoff, step.StepOpts.Backoff)
+		assert.Equal(t, root.Steps[0].StepOpts.Timeout, step.StepOpts.Timeout)
+		assert.Equal(t, OnUnresolvedFail, step.Branches.OnUnresolved)
+		assert.NotNil(t, step.Function)
+		assert.NotNil(t, step.Branches.Resolver)
+		assert.NotNil(t, step.Branches.Branches[0].Steps[0].Function)
+		assert.NotNil(t, loaded.Steps[1].SkipIf)
+		assert.NotNil(t, loaded.Steps[1].Parallel.Steps[0].Function)
+		assert.NotNil(t, loaded.Steps[2].Loop.While)
+		assert.NotNil(t, loaded.Steps[2].Loop.Steps[0].Function)
+		assert.Equal(t, time.Hour, loaded.BranchOpts.Timeout)
+
+		reloaded, err := loaded.Marshal(format)
+		assert.NoError(t, err)
+		assert.Equal(t, string(definition), string(reloaded))
+
+		ctx := NewGoStepsContext()
+		report := loaded.Execute(ctx)
+		assert.Equal(t, StepStateComplete, report.Outcome)
+		assert.Equal(t, 144, ctx.GetData("result"))
+	}
+}
+
+func Test_LoadBranch_Yaml(t *testing.T) {
+
+	definition := `
+branchName: root
+steps:
+  - name: add
+    stepArgs:
+      n1: 2
+      n2: 3
+    stepConfig:
+      maxAttempts: 2
+      retrySleep: 10ms
+      errorsToRetry: [error1]
+      backoff:
+        type: linear
+        initial: 1s
+        increment: 500ms
+`
+
+	loaded, err := LoadBranch(strings.NewReader(definition), FormatYAML, newTestRegistry())
+	assert.NoError(t, err)
+
+	assert.Equal(t, 10*time.Millisecond, loaded.Steps[0].StepOpts.RetrySleep)
+	assert.Equal(t, []error{error1}, loaded.Steps[0].StepOpts.ErrorsToRetry)
+	assert.Equal(t, LinearBackoff{Initial: time.Second, Increment: 500 * time.Millisecond}, loaded.Steps[0].StepOpts.Backoff)
+
+	ctx := NewGoStepsContext()
+	loaded.Execute(ctx)
+	assert.Equal(t, 5, ctx.GetData("result"))
+}
+
+func Test_LoadBranch_SubWorkflow(t *testing.T) {
+
+	definition := `
+branchName: root
+steps:
+  - name: login
+    subWorkflow:
+      workflow: auth
+      inputs:
+        user: admin
+      outputs:
+        adminToken: token
+`
+
+	registry := newTestRegistry().RegisterWorkflow("auth", newAuthWorkflow())
+
+	loaded, err := LoadBranch(strings.NewReader(definition), FormatYAML, registry)
+	assert.NoError(t, err)
+	assert.Equal(t, "auth", loaded.Steps[0].SubWorkflow.WorkflowName)
+
+	ctx := NewGoStepsContext()
+	ctx.SetData("admin", "alice")
+
+	report := loaded.Execute(ctx)
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, "token-alice", ctx.GetData("adminToken"))
+
+	definitionJson, err := loaded.Marshal(FormatJSON)
+	assert.NoError(t, err)
+
+	reloaded, err := LoadBranchFromBytes(definitionJson, FormatJSON, registry)
+	assert.NoError(t, err)
+	assert.Equal(t, loaded.Steps[0].SubWorkflow, reloaded.Steps[0].SubWorkflow)
+}
+
+func Test_LoadBranch_Errors(t *testing.T) {
+
+	testCases := []struct {
+		Definition    string
+		Format        Format
+		ExpectedError string
+	}{
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "function": "unknown"}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: function [unknown] of step [step1] is not registered",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "branches": {"resolver": "unknown", "branches": []}}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: resolver [unknown] of step [step1] is not registered",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "loop": {"while": "unknown", "steps": []}}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: condition [unknown] of step [step1] is not registered",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "when": "unknown"}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: condition [unknown] of step [step1] is not registered",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "branches": {"branches": [{"branchName": "branch1", "when": "unknown"}]}}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: condition [unknown] of branch [branch1] is not registered",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "subWorkflow": {"workflow": "unknown"}}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: workflow [unknown] of step [step1] is not registered",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "stepConfig": {"errorsToRetry": ["unknown"]}}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: invalid stepConfig of step [step1]: error [unknown] is not registered",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "stepConfig": {"retrySleep": "2 seconds"}}]}`,
+			Format:        FormatJSON,
+			ExpectedError: "error: failed to decode json step-tree",
+		},
+		{
+			Definition:    `{"branchName": "root", "steps": [{"name": "step1", "stepConfig": {"maxAttemps": 3}}]}`,
+			Format:        FormatJSON,
+			ExpectedError: `error: failed to decode json step-tree: json: unknown field "maxAttemps"`,
+		},
+		{
+			Definition:    "branchName: root\nsteps:\n  - name: step1\n    stepConfig:\n      maxAttemps: 3\n",
+			Format:        FormatYAML,
+			ExpectedError: "field maxAttemps not found",
+		},
+		{
+			Definition:    `{"branchName": "root"}`,
+			Format:        "toml",
+			ExpectedError: "error: unknown step-tree format [toml]",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := LoadBranch(strings.NewReader(tc.Definition), tc.Format, newTestRegistry())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), tc.ExpectedError)
+	}
+
+	_, err := LoadBranchFromBytes([]byte(`{"branchName": "root", "steps": [{"name": "step1", "stepConfig": {"errorPatternsToRetry": ["("]}}]}`), FormatJSON, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "error: invalid stepConfig of step [step1]: error parsing regexp")
+}
+
+func Test_ToJson(t *testing.T) {
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name:         "add",
+			FunctionName: "add",
+			StepOpts: StepOpts{
+				MaxRunAttempts: 2,
+				ErrorsToRetry:  []error{ErrStepTimeout},
+				RetrySleep:     time.Second,
+			},
+		},
+	})
+
+	// ToJson writes the definition of Marshal, that is loaded back by LoadBranch
+	definition, err := root.ToJson()
+	assert.NoError(t, err)
+
+	marshalled, err := root.Marshal(FormatJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, string(marshalled), definition)
+
+	registry := NewRegistry().RegisterFunction("add", func(c GoStepsCtx) StepResult {
+		return MarkStateComplete()
+	})
+
+	loaded, err := LoadBranch(strings.NewReader(definition), FormatJSON, registry)
+	assert.NoError(t, err)
+	assert.Equal(t, StepName("add"), loaded.Steps[0].Name)
+	assert.Equal(t, []error{ErrStepTimeout}, loaded.Steps[0].StepOpts.ErrorsToRetry)
+	assert.Equal(t, 2, loaded.Steps[0].StepOpts.MaxRunAttempts)
+	assert.Equal(t, time.Second, loaded.Steps[0].StepOpts.RetrySleep)
+
+	_, err = root.Marshal("toml")
+	assert.EqualError(t, err, "error: unknown step-tree format [toml]")
+}
@@ -1,10 +1,10 @@
 package gosteps
 
 import (
+	"context"
 	"io"
 	"os"
-
-	// "strings"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -15,15 +15,6 @@ var (
 	// defaultLogOut is the default output for the logger
 	defaultLogOut = os.Stdout
 
-	// stateToLevelMap maps the StepState to the log level
-	stateToLevelMap = map[StepState]zerolog.Level{
-		StepStateComplete: zerolog.InfoLevel,
-		StepStateFailed:   zerolog.WarnLevel,
-		StepStateSkipped:  zerolog.DebugLevel,
-		StepStatePending:  zerolog.DebugLevel,
-		StepStateError:    zerolog.ErrorLevel,
-	}
-
 	// Log Level of GoSteps Logger implementation of zerolog.Level
 	DebugLevel = LogLevel(zerolog.DebugLevel)
 	InfoLevel  = LogLevel(zerolog.InfoLevel)
@@ -31,13 +22,34 @@ var (
 	ErrorLevel = LogLevel(zerolog.ErrorLevel)
 )
 
+// Logger interface defines the logging backend of GoSteps, the logs of the steps and of
+// ctx.Log are written to the logger, with the fields of the step. Implementations
+// must be safe for concurrent use, steps of parallel groups and graphs log concurrently
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, message string, fields map[string]interface{})
+}
+
+// DefaultStateLevels returns the default log levels of the step states, used
+// for the step logs if LoggerOpts.StateLevels is not set for the state
+func DefaultStateLevels() map[StepState]LogLevel {
+	return map[StepState]LogLevel{
+		StepStateComplete:  InfoLevel,
+		StepStateFailed:    WarnLevel,
+		StepStateSkipped:   DebugLevel,
+		StepStatePending:   DebugLevel,
+		StepStateError:     ErrorLevel,
+		StepStateCancelled: WarnLevel,
+	}
+}
+
 type goStepsLogger struct {
 	config *LoggerOpts
-	logger zerolog.Logger
+	logger Logger
 }
 
 type LoggerOpts struct {
 	StepLoggingEnabled bool
+	StateLevels        map[StepState]LogLevel // log levels of the step states, overrides DefaultStateLevels
 }
 
 // NewGoStepsLogger returns a new instance of the GoStepsLogger
@@ -48,12 +60,21 @@ type LoggerOpts struct {
 // loggerOpts: is of type *LoggerOpts, if nil, default options are used
 // to enable step level logging, set StepLoggingEnabled to true
 func NewGoStepsLogger(out io.Writer, loggerOpts *LoggerOpts) goStepsLogger {
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
-
 	if out == nil {
 		out = defaultLogOut
 	}
 
+	// the unix timestamp is added by a hook, so that the global zerolog.TimeFieldFormat is not changed
+	logger := zerolog.New(out).Hook(zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, message string) {
+		e.Int64(zerolog.TimestampFieldName, time.Now().Unix())
+	}))
+
+	return newGoStepsLogger(NewZerologLogger(logger), loggerOpts)
+}
+
+// newGoStepsLogger returns a new instance of the GoStepsLogger with the logger
+// provided, if loggerOpts is nil, default options are used
+func newGoStepsLogger(logger Logger, loggerOpts *LoggerOpts) goStepsLogger {
 	if loggerOpts == nil {
 		loggerOpts = &LoggerOpts{
 			StepLoggingEnabled: false,
@@ -62,10 +83,37 @@ func NewGoStepsLogger(out io.Writer, loggerOpts *LoggerOpts) goStepsLogger {
 
 	return goStepsLogger{
 		config: loggerOpts,
-		logger: zerolog.New(out).With().Timestamp().Logger(),
+		logger: logger,
+	}
+}
+
+// stateLevel returns the log level of the step state, from the options if set, else the default
+func (opts *LoggerOpts) stateLevel(state StepState) LogLevel {
+	if level, ok := opts.StateLevels[state]; ok {
+		return level
+	}
+
+	return DefaultStateLevels()[state]
+}
+
+// zerologLogger type defines the Logger backed by a zerolog.Logger
+type zerologLogger struct {
+	logger zerolog.Logger
+}
+
+// NewZerologLogger returns a Logger writing to the zerolog logger provided,
+// the configuration of the logger, and of zerolog, is used as is
+func NewZerologLogger(logger zerolog.Logger) Logger {
+	return zerologLogger{
+		logger: logger,
 	}
 }
 
+// Log writes the message with the fields to the zerolog logger
+func (l zerologLogger) Log(ctx context.Context, level LogLevel, message string, fields map[string]interface{}) {
+	l.logger.WithLevel(zerolog.Level(level)).Fields(fields).Msg(message)
+}
+
 type stepLogStruct struct {
 	Name     string
 	State    string
@@ -73,31 +121,36 @@ type stepLogStruct struct {
 	Error    error
 	RunCount int
 	MaxRun   int
+	Wait     time.Duration
 }
 
-// getStepLogStruct returns the loggable struct for the step
-func (step *Step) getStepLogStruct() stepLogStruct {
+// getStepLogStruct returns the loggable struct for the step and its result
+func (step *Step) getStepLogStruct(stepResult StepResult) stepLogStruct {
 	var stepError error
-	if step.stepResult.StepError != nil {
-		stepError = step.stepResult.StepError
+	if stepResult.StepError != nil {
+		stepError = stepResult.StepError
 	}
 
 	return stepLogStruct{
 		Name: string(step.Name),
 
-		State:   string(step.stepResult.StepState),
-		Message: step.stepResult.StepMessage,
+		State:   string(stepResult.StepState),
+		Message: stepResult.StepMessage,
 		Error:   stepError,
 
 		RunCount: step.stepRunProgress.runCount,
 		MaxRun:   step.StepOpts.MaxRunAttempts,
+		Wait:     step.stepRunProgress.waitDuration,
 	}
 }
 
 // loggableFormat returns the loggable format for the step
 func (s stepLogStruct) loggableFormat() map[string]interface{} {
 	loggableFields := map[string]interface{}{
-		"maxRun": s.MaxRun,
+		"step":     s.Name,
+		"state":    s.State,
+		"runCount": s.RunCount,
+		"maxRun":   s.MaxRun,
 	}
 
 	if s.Error != nil {
@@ -108,25 +161,31 @@ func (s stepLogStruct) loggableFormat() map[string]interface{} {
 		loggableFields["message"] = s.Message
 	}
 
+	// time waiting on the rate limiters, separate from the time running the step
+	if s.Wait > 0 {
+		loggableFields["waitDuration"] = s.Wait.String()
+	}
+
 	return loggableFields
 }
 
 // log logs the step with the step name, state, run count and the log fields
 // it is only used by the step if the step logging is enabled
 func (c *GoStepsCtx) log(step *Step) {
-	lStruct := step.getStepLogStruct()
-
-	c.logger.logger.WithLevel(
-		stateToLevelMap[step.stepResult.StepState],
-	).Str(
-		"step", string(lStruct.Name),
-	).Str(
-		"state", lStruct.State,
-	).Int(
-		"runCount", lStruct.RunCount,
-	).Fields(
+	c.logResult(step, *step.stepResult)
+}
+
+// logResult logs the result of the step, it is used by the built-in logging
+// middleware, for each attempt of the step, if the step logging is enabled
+func (c *GoStepsCtx) logResult(step *Step, stepResult StepResult) {
+	lStruct := step.getStepLogStruct(stepResult)
+
+	c.logger.logger.Log(
+		c.Context(),
+		c.logger.config.stateLevel(stepResult.StepState),
+		"",
 		lStruct.loggableFormat(),
-	).Msg("")
+	)
 }
 
 // Log logs the message with the step name and the log level, if provided.
@@ -137,7 +196,7 @@ func (c *GoStepsCtx) Log(message string, levels ...LogLevel) {
 		ll = levels[0]
 	}
 
-	c.logger.logger.WithLevel(zerolog.Level(ll)).Str(
-		"step", string(c.currentStep),
-	).Msg(message)
+	c.logger.logger.Log(c.Context(), ll, message, map[string]interface{}{
+		"step": string(c.currentStep),
+	})
 }
@@ -0,0 +1,62 @@
+//go:build go1.21
+
+package gosteps
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/rs/zerolog"
+)
+
+// slogLogger type defines the Logger backed by a *slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to the slog logger provided, if nil, slog.Default() is used
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return slogLogger{
+		logger: logger,
+	}
+}
+
+// Log writes the message with the fields, as attributes sorted by key, to the slog logger
+func (l slogLogger) Log(ctx context.Context, level LogLevel, message string, fields map[string]interface{}) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, key := range keys {
+		value := fields[key]
+		if message, ok := value.(*string); ok && message != nil {
+			value = *message
+		}
+
+		attrs = append(attrs, slog.Any(key, value))
+	}
+
+	l.logger.LogAttrs(ctx, slogLevel(level), message, attrs...)
+}
+
+// slogLevel returns the slog.Level of the log level
+func slogLevel(level LogLevel) slog.Level {
+	switch zerolog.Level(level) {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		return slog.LevelDebug
+	case zerolog.InfoLevel, zerolog.NoLevel:
+		return slog.LevelInfo
+	case zerolog.WarnLevel:
+		return slog.LevelWarn
+	default: // zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel
+		return slog.LevelError
+	}
+}
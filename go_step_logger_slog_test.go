@@ -0,0 +1,53 @@
+//go:build go1.21
+
+package gosteps
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SlogLogger(t *testing.T) {
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				c.Log("processing", DebugLevel)
+				return MarkStateSkipped().WithMessage("nothing to do")
+			},
+		},
+	})
+
+	ctx := NewGoStepsContext().Use(NewSlogLogger(logger), LoggerOpts{
+		StepLoggingEnabled: true,
+		StateLevels: map[StepState]LogLevel{
+			StepStateSkipped: WarnLevel,
+		},
+	})
+	root.Execute(ctx)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "DEBUG", entry["level"])
+	assert.Equal(t, "processing", entry["msg"])
+	assert.Equal(t, "step1", entry["step"])
+
+	entry = map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "StepStateSkipped", entry["state"])
+	assert.Equal(t, "nothing to do", entry["message"])
+	assert.Equal(t, float64(1), entry["runCount"])
+}
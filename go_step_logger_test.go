@@ -0,0 +1,52 @@
+package gosteps
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewGoStepsLogger(t *testing.T) {
+
+	timeFieldFormat := zerolog.TimeFieldFormat
+
+	var out bytes.Buffer
+	logger := NewGoStepsLogger(&out, &LoggerOpts{
+		StepLoggingEnabled: true,
+		StateLevels: map[StepState]LogLevel{
+			StepStateComplete: DebugLevel,
+		},
+	})
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	})
+	root.Execute(NewGoStepsContext().Use(logger))
+
+	// the global zerolog configuration is not changed
+	assert.Equal(t, timeFieldFormat, zerolog.TimeFieldFormat)
+
+	line := strings.TrimSpace(out.String())
+	assert.Contains(t, line, `"level":"debug"`)
+	assert.Contains(t, line, `"step":"step1"`)
+	assert.Contains(t, line, `"time":`)
+}
+
+func Test_ZerologLogger(t *testing.T) {
+
+	var out bytes.Buffer
+	ctx := NewGoStepsContext().Use(NewZerologLogger(zerolog.New(&out)))
+
+	goStepsCtx := ctx.getCtx()
+	goStepsCtx.Log("message", WarnLevel)
+
+	assert.Equal(t, `{"level":"warn","step":"","message":"message"}`, strings.TrimSpace(out.String()))
+}
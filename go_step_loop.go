@@ -0,0 +1,212 @@
+package gosteps
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrInvalidControl is the error of a step with a control result that can not be applied,
+	// example a goto to an unknown step, or a break outside of a loop
+	ErrInvalidControl = errors.New("invalid control flow")
+
+	// ErrStepBudgetExceeded is the error of a step not executed, as the run
+	// already executed the maximum number of steps of the StepBudget
+	ErrStepBudgetExceeded = errors.New("step budget exceeded")
+)
+
+// StepBudget type defines the maximum number of steps executed by a run, across all the
+// branches, loops, gotos, parallel groups and graphs of the step-chain, to protect against
+// infinite loops. Set the budget of the runs of a context with ctx.Use(gosteps.StepBudget(n))
+type StepBudget int
+
+// DefaultStepBudget is the step budget of the runs, if not set in the context
+const DefaultStepBudget StepBudget = 10000
+
+// Loop type defines steps executed repeatedly, while the While condition is true, and at most
+// MaxIterations times. The steps of an iteration can stop the loop with StepResult.Break, or
+// skip to the next iteration with StepResult.Continue. If neither While nor MaxIterations
+// is set, the loop runs until a step breaks out of it, or the step budget is exceeded
+type Loop struct {
+	Steps         Steps       `json:"steps"`
+	While         ConditionFn `json:"-"`               // checked before each iteration, the loop stops once false
+	WhileName     string      `json:"while,omitempty"` // name of the While condition in the Registry, used to load the loop
+	MaxIterations int         `json:"maxIterations"`   // maximum iterations of the loop, unlimited if not set
+}
+
+// stepBudget type defines the steps executed by a run, shared by the forked contexts of the run
+type stepBudget struct {
+	mutex    sync.Mutex
+	limit    StepBudget
+	executed int
+}
+
+// newStepBudget returns the step budget of a run, with the default limit if not set
+func newStepBudget(limit StepBudget) *stepBudget {
+	if limit <= 0 {
+		limit = DefaultStepBudget
+	}
+
+	return &stepBudget{
+		limit: limit,
+	}
+}
+
+// take takes a step execution from the budget, returns an error if the budget is exceeded
+func (budget *stepBudget) take() error {
+	if budget == nil {
+		return nil
+	}
+
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+
+	if budget.executed >= int(budget.limit) {
+		return fmt.Errorf("%w: run executed %d steps", ErrStepBudgetExceeded, budget.executed)
+	}
+
+	budget.executed += 1
+	return nil
+}
+
+// controlFlow type defines the control flow set by the result of a step, pending until it
+// is applied by the steps, or the loop, it applies to. It is shared by the copies of the
+// context of a run, forked contexts of parallel groups and graphs have their own
+type controlFlow struct {
+	control *StepControl
+}
+
+// pending returns the pending control flow, nil if none
+func (flow *controlFlow) pending() *StepControl {
+	if flow == nil {
+		return nil
+	}
+
+	return flow.control
+}
+
+// set sets the pending control flow
+func (flow *controlFlow) set(control *StepControl) {
+	if flow != nil {
+		flow.control = control
+	}
+}
+
+// stepScope type defines the steps of a branch or loop being executed, and the
+// enclosing ones, the targets of the control results are looked up in the scopes
+type stepScope struct {
+	steps  Steps
+	loop   bool // the scope is the boundary of a loop, and has no steps
+	parent *stepScope
+}
+
+// hasStep checks if the step is in the scope, or in the enclosing scopes
+func (scope *stepScope) hasStep(name StepName) bool {
+	for ; scope != nil; scope = scope.parent {
+		if scope.steps.indexOf(name) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inLoop checks if the scope is within a loop
+func (scope *stepScope) inLoop() bool {
+	for ; scope != nil; scope = scope.parent {
+		if scope.loop {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexOf returns the index of the step with the name, -1 if not found
+func (steps Steps) indexOf(name StepName) int {
+	for i := range steps {
+		if steps[i].Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// setControl sets the control flow of the result of the step, if the step is done, or
+// overrides the result of the step with an error if the control can not be applied
+func (step *Step) setControl(c *GoStepsCtx) {
+	if step.stepResult == nil || step.stepResult.Control == nil || !step.stepResult.isDone() {
+		return
+	}
+
+	control := step.stepResult.Control
+
+	var err error
+	switch control.Action {
+	case ControlGoto:
+		if !c.scope.hasStep(control.Target) {
+			err = fmt.Errorf("%w: goto step [%s] of step [%s] is not in the step-chain", ErrInvalidControl, control.Target, step.Name)
+		}
+	case ControlBreak, ControlContinue:
+		if !c.scope.inLoop() {
+			err = fmt.Errorf("%w: %s of step [%s] is not within a loop", ErrInvalidControl, control.Action, step.Name)
+		}
+	default:
+		err = fmt.Errorf("%w: unknown action [%s] of step [%s]", ErrInvalidControl, control.Action, step.Name)
+	}
+
+	if err != nil {
+		step.overrideResult(c, MarkStateFailed().WithError(err))
+		return
+	}
+
+	c.flow.set(control)
+}
+
+// execute runs the iterations of the loop, each iteration with a copy of the context and a separate
+// run, merged into the context once the iteration is done. The loop completes once the While condition
+// is false, the MaxIterations are run, or a step breaks out of it, else the loop is terminated with
+// the state of the step that terminated the iteration. The steps are copied for each execution, so
+// that the retries of the loop are isolated from the abandoned attempts that timed out
+func (loop *Loop) execute(c GoStepsCtx) StepResult {
+	steps := loop.Steps.clone()
+
+	iteration := 0
+	for loop.MaxIterations <= 0 || iteration < loop.MaxIterations {
+		if loop.While != nil && !loop.While(c) {
+			break
+		}
+
+		iteration += 1
+
+		iterationCtx := c
+		iterationCtx.run = newGoStepsRun()
+		iterationCtx.flow = &controlFlow{}
+		iterationCtx.scope = &stepScope{loop: true, parent: c.scope}
+		iterationCtx.iteration = iteration
+
+		terminated := steps.execute(iterationCtx)
+		c.merge(iterationCtx.run)
+
+		if terminated {
+			report := iterationCtx.run.report()
+			return markState(report.Outcome).WithError(report.Error)
+		}
+
+		control := iterationCtx.flow.pending()
+		if control == nil || control.Action == ControlContinue {
+			continue
+		}
+
+		// a goto out of the loop stops the loop, and is applied by the enclosing steps
+		if control.Action == ControlGoto {
+			c.flow.set(control)
+		}
+
+		break
+	}
+
+	return MarkStateComplete().WithMessage(fmt.Sprintf("loop ran %d iterations", iteration))
+}
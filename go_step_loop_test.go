@@ -0,0 +1,361 @@
+package gosteps
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Loop(t *testing.T) {
+
+	countKey := Key[int]("count")
+	count := func(c GoStepsCtx) StepResult {
+		return MarkStateComplete().WithData(countKey.Data(countKey.GetOr(c, 0) + 1))
+	}
+
+	testCases := []struct {
+		name               string
+		loop               *Loop
+		expectedCount      int
+		expectedIterations []int
+	}{
+		{
+			name: "max iterations",
+			loop: &Loop{
+				Steps:         Steps{{Name: "count", Function: count}},
+				MaxIterations: 3,
+			},
+			expectedCount:      3,
+			expectedIterations: []int{1, 2, 3},
+		},
+		{
+			name: "while condition",
+			loop: &Loop{
+				Steps: Steps{{Name: "count", Function: count}},
+				While: func(c GoStepsCtx) bool {
+					return countKey.GetOr(c, 0) < 2
+				},
+			},
+			expectedCount:      2,
+			expectedIterations: []int{1, 2},
+		},
+		{
+			name: "break",
+			loop: &Loop{
+				Steps: Steps{
+					{Name: "count", Function: count},
+					{
+						Name: "check",
+						Function: func(c GoStepsCtx) StepResult {
+							if countKey.MustGet(c) == 4 {
+								return MarkStateComplete().Break()
+							}
+
+							return MarkStateComplete()
+						},
+					},
+				},
+			},
+			expectedCount:      4,
+			expectedIterations: []int{1, 1, 2, 2, 3, 3, 4, 4},
+		},
+		{
+			name: "continue",
+			loop: &Loop{
+				Steps: Steps{
+					{
+						Name: "check",
+						Function: func(c GoStepsCtx) StepResult {
+							return MarkStateComplete().Continue()
+						},
+					},
+					{Name: "count", Function: count},
+				},
+				MaxIterations: 2,
+			},
+			expectedCount:      0,
+			expectedIterations: []int{1, 2},
+		},
+		{
+			name: "break from a branch",
+			loop: &Loop{
+				Steps: Steps{
+					{
+						Name:     "count",
+						Function: count,
+						Branches: &Branches{
+							Resolver: func(c GoStepsCtx) BranchName {
+								return "stop"
+							},
+							Branches: []Branch{
+								{
+									BranchName: "stop",
+									Steps: Steps{
+										{
+											Name: "stop",
+											Function: func(c GoStepsCtx) StepResult {
+												return MarkStateComplete().Break()
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				MaxIterations: 5,
+			},
+			expectedCount:      1,
+			expectedIterations: []int{1, 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			steps := Steps{
+				{Name: "loop", Loop: tc.loop},
+			}
+
+			c := NewGoStepsContext()
+			report := NewStepsProcessor(steps).Execute(c)
+
+			assert.Equal(t, StepStateComplete, report.Outcome)
+			assert.Equal(t, tc.expectedCount, countKey.GetOr(c, 0))
+
+			iterations := []int{}
+			for _, progress := range report.Steps[:len(report.Steps)-1] {
+				iterations = append(iterations, progress.Iteration)
+				assert.Equal(t, 1, progress.RunCount)
+			}
+
+			assert.Equal(t, tc.expectedIterations, iterations)
+			assert.Equal(t, StepName("loop"), report.Steps[len(report.Steps)-1].StepName)
+		})
+	}
+}
+
+func Test_Loop_TimeoutRetry(t *testing.T) {
+
+	var attempts int32
+	abandoned := make(chan struct{})
+
+	steps := Steps{
+		{
+			Name: "poll",
+			Loop: &Loop{
+				Steps: Steps{
+					{
+						Name: "wait",
+						Function: func(c GoStepsCtx) StepResult {
+							if atomic.AddInt32(&attempts, 1) == 1 {
+								defer close(abandoned)
+								time.Sleep(50 * time.Millisecond)
+							}
+
+							return MarkStateComplete()
+						},
+					},
+				},
+				MaxIterations: 1,
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 2,
+				Timeout:        20 * time.Millisecond,
+				ErrorsToRetry:  []error{ErrStepTimeout},
+			},
+		},
+		{
+			Name: "next",
+			Function: func(c GoStepsCtx) StepResult {
+				<-abandoned
+				time.Sleep(20 * time.Millisecond)
+
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+
+	// the abandoned attempt of the loop runs its own copy of the steps, and does not change the report
+	waits := 0
+	for _, progress := range report.Steps {
+		if progress.StepName == "wait" {
+			waits += 1
+		}
+	}
+	assert.Equal(t, 1, waits)
+}
+
+func Test_Loop_Terminated(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "loop",
+			Loop: &Loop{
+				Steps: Steps{
+					{
+						Name: "fail",
+						Function: func(c GoStepsCtx) StepResult {
+							return MarkStateError().WithError(error1)
+						},
+					},
+				},
+				MaxIterations: 3,
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, StepName("loop"), report.TerminatingStep.StepName)
+	assert.True(t, errors.Is(report.Error, error1))
+	assert.Len(t, report.Steps, 2)
+}
+
+func Test_GotoStep(t *testing.T) {
+
+	polls := 0
+
+	steps := Steps{
+		{
+			Name: "poll",
+			Function: func(c GoStepsCtx) StepResult {
+				polls += 1
+				return MarkStateComplete()
+			},
+		},
+		{
+			Name: "check",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+			Branches: &Branches{
+				Resolver: func(c GoStepsCtx) BranchName {
+					if polls < 3 {
+						return "wait"
+					}
+
+					return "ready"
+				},
+				Branches: []Branch{
+					{
+						BranchName: "wait",
+						Steps: Steps{
+							{
+								Name: "wait",
+								Function: func(c GoStepsCtx) StepResult {
+									return MarkStateComplete().GotoStep("poll")
+								},
+							},
+						},
+					},
+					{BranchName: "ready"},
+				},
+			},
+		},
+		{
+			Name: "done",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	store := NewMemoryCheckpointStore()
+	c := NewGoStepsContext().Use(NewCheckpointer(store, nil))
+
+	report := NewStepsProcessor(steps).Execute(c)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 3, polls)
+
+	stepNames := []StepName{}
+	for _, progress := range report.Steps {
+		stepNames = append(stepNames, progress.StepName)
+	}
+
+	assert.Equal(t, []StepName{"poll", "check", "wait", "poll", "check", "wait", "poll", "check", "done"}, stepNames)
+	assert.Equal(t, []BranchSelection{
+		{StepName: "check", BranchName: "wait"},
+		{StepName: "check", BranchName: "wait"},
+		{StepName: "check", BranchName: "ready"},
+	}, report.BranchPath)
+}
+
+func Test_Control_Errors(t *testing.T) {
+
+	testCases := []struct {
+		name          string
+		stepResult    StepResult
+		expectedError error
+	}{
+		{
+			name:          "goto unknown step",
+			stepResult:    MarkStateComplete().GotoStep("unknown"),
+			expectedError: ErrInvalidControl,
+		},
+		{
+			name:          "break outside of a loop",
+			stepResult:    MarkStateComplete().Break(),
+			expectedError: ErrInvalidControl,
+		},
+		{
+			name:          "continue outside of a loop",
+			stepResult:    MarkStateComplete().Continue(),
+			expectedError: ErrInvalidControl,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			steps := Steps{
+				{
+					Name: "step1",
+					Function: func(c GoStepsCtx) StepResult {
+						return tc.stepResult
+					},
+				},
+			}
+
+			report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+			assert.Equal(t, StepStateFailed, report.Outcome)
+			assert.True(t, errors.Is(report.Error, tc.expectedError))
+		})
+	}
+}
+
+func Test_StepBudget(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "loop",
+			Loop: &Loop{
+				Steps: Steps{
+					{
+						Name: "forever",
+						Function: func(c GoStepsCtx) StepResult {
+							return MarkStateComplete()
+						},
+					},
+				},
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext().Use(StepBudget(5)))
+
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.True(t, errors.Is(report.Error, ErrStepBudgetExceeded))
+
+	// the loop step and 4 iterations are run, the 5th iteration exceeds the budget
+	assert.Len(t, report.Steps, 6)
+	assert.Equal(t, 5, report.Steps[4].Iteration)
+}
@@ -0,0 +1,119 @@
+package gosteps
+
+import (
+	"context"
+	"time"
+)
+
+// Middleware type defines a wrapper of the step functions, called on each attempt of the steps,
+// a middleware can run code before and after the next function, change the context or the
+// result, or return a result without calling next. Middlewares are registered in the context
+// with Use, or on a branch with Branch.Use, and apply to all the steps of the step-tree
+type Middleware func(next StepFn) StepFn
+
+// Hooks type defines functions called on the lifecycle events of the step-chain execution,
+// hooks are registered in the context with Use, unset hooks are not called. Steps of
+// parallel groups and graphs are run concurrently, their hooks must be safe for concurrent use
+type Hooks struct {
+	OnStepStart      func(c GoStepsCtx, step StepName)                              // before the first attempt of a step with a function
+	OnStepEnd        func(c GoStepsCtx, progress StepProgress)                      // after a step with a function is executed, with its final result
+	OnRetry          func(c GoStepsCtx, progress StepProgress, sleep time.Duration) // before sleeping between attempts of a step
+	OnBranchResolved func(c GoStepsCtx, selection BranchSelection)                  // after a branch is selected by the resolver of a step, after OnStepEnd
+	OnChainEnd       func(c GoStepsCtx, report *ExecutionReport)                    // after the step-chain execution, with its report
+}
+
+// Use adds the middlewares to the branch, the middlewares apply to the steps of the
+// branch and the branches nested in them, after the middlewares of the context
+func (branch *Branch) Use(middlewares ...Middleware) *Branch {
+	branch.Middlewares = append(branch.Middlewares, middlewares...)
+	return branch
+}
+
+// WithContext returns a copy of the context with the context.Context replaced,
+// middlewares can use it to pass values or deadlines to the next step function
+func (ctx GoStepsCtx) WithContext(c context.Context) GoStepsCtx {
+	ctx.ctx = c
+	return ctx
+}
+
+// withMiddlewares returns a copy of the context with the middlewares added
+// after the existing ones, the middlewares of the context are not modified
+func (ctx GoStepsCtx) withMiddlewares(middlewares []Middleware) GoStepsCtx {
+	if len(middlewares) == 0 {
+		return ctx
+	}
+
+	combined := make([]Middleware, 0, len(ctx.middlewares)+len(middlewares))
+	combined = append(combined, ctx.middlewares...)
+	ctx.middlewares = append(combined, middlewares...)
+
+	return ctx
+}
+
+// wrap wraps the step function with the middlewares of the context, the first
+// middleware is the outermost, the built-in logger wraps all the middlewares
+func (ctx GoStepsCtx) wrap(fn StepFn) StepFn {
+	for i := len(ctx.middlewares) - 1; i >= 0; i-- {
+		fn = ctx.middlewares[i](fn)
+	}
+
+	return loggingMiddleware(fn)
+}
+
+// loggingMiddleware logs the result of each attempt of the step, if the step logging is enabled
+func loggingMiddleware(next StepFn) StepFn {
+	return func(c GoStepsCtx) StepResult {
+		stepResult := next(c)
+
+		if c.step != nil && c.logger.config.StepLoggingEnabled {
+			c.logResult(c.step, stepResult)
+		}
+
+		return stepResult
+	}
+}
+
+// onStepStart calls the OnStepStart hooks of the context
+func (ctx GoStepsCtx) onStepStart(step StepName) {
+	for _, hooks := range ctx.hooks {
+		if hooks.OnStepStart != nil {
+			hooks.OnStepStart(ctx, step)
+		}
+	}
+}
+
+// onStepEnd calls the OnStepEnd hooks of the context
+func (ctx GoStepsCtx) onStepEnd(progress StepProgress) {
+	for _, hooks := range ctx.hooks {
+		if hooks.OnStepEnd != nil {
+			hooks.OnStepEnd(ctx, progress)
+		}
+	}
+}
+
+// onRetry calls the OnRetry hooks of the context
+func (ctx GoStepsCtx) onRetry(progress StepProgress, sleep time.Duration) {
+	for _, hooks := range ctx.hooks {
+		if hooks.OnRetry != nil {
+			hooks.OnRetry(ctx, progress, sleep)
+		}
+	}
+}
+
+// onBranchResolved calls the OnBranchResolved hooks of the context
+func (ctx GoStepsCtx) onBranchResolved(selection BranchSelection) {
+	for _, hooks := range ctx.hooks {
+		if hooks.OnBranchResolved != nil {
+			hooks.OnBranchResolved(ctx, selection)
+		}
+	}
+}
+
+// onChainEnd calls the OnChainEnd hooks of the context
+func (ctx GoStepsCtx) onChainEnd(report *ExecutionReport) {
+	for _, hooks := range ctx.hooks {
+		if hooks.OnChainEnd != nil {
+			hooks.OnChainEnd(ctx, report)
+		}
+	}
+}
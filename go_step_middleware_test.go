@@ -0,0 +1,175 @@
+package gosteps
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Middleware(t *testing.T) {
+
+	calls := []string{}
+	record := func(name string) Middleware {
+		return func(next StepFn) StepFn {
+			return func(c GoStepsCtx) StepResult {
+				calls = append(calls, fmt.Sprintf("%s:before:%s", name, c.currentStep))
+				stepResult := next(c)
+				calls = append(calls, fmt.Sprintf("%s:after:%s", name, c.currentStep))
+
+				return stepResult
+			}
+		}
+	}
+
+	deny := func(next StepFn) StepFn {
+		return func(c GoStepsCtx) StepResult {
+			if c.GetData("authorized") != true {
+				return MarkStateFailed().WithMessage("unauthorized")
+			}
+
+			return next(c)
+		}
+	}
+
+	step := func(name string) StepFn {
+		return func(c GoStepsCtx) StepResult {
+			calls = append(calls, "run:"+name)
+			return MarkStateComplete()
+		}
+	}
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name:     "step1",
+			Function: step("step1"),
+			Branches: &Branches{
+				DefaultBranch: "secure",
+				Branches: []Branch{
+					{
+						BranchName: "secure",
+						Steps: Steps{
+							{Name: "step2", Function: step("step2")},
+						},
+					},
+				},
+			},
+		},
+	})
+	root.Steps[0].Branches.Branches[0].Use(deny)
+
+	ctx := NewGoStepsContext().Use(record("outer"), Middleware(record("inner")))
+	report := root.Execute(ctx)
+
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.Equal(t, []string{
+		"outer:before:step1", "inner:before:step1", "run:step1", "inner:after:step1", "outer:after:step1",
+		"outer:before:step2", "inner:before:step2", "inner:after:step2", "outer:after:step2",
+	}, calls)
+}
+
+func Test_Hooks(t *testing.T) {
+
+	var mutex sync.Mutex
+	events := []string{}
+	record := func(event string) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		events = append(events, event)
+	}
+
+	attempts := 0
+	root := NewStepsProcessor(Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				attempts += 1
+				if attempts < 2 {
+					return MarkStatePending()
+				}
+
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 2,
+				RetrySleep:     time.Millisecond,
+			},
+			Branches: &Branches{
+				DefaultBranch: "next",
+				Branches: []Branch{
+					{
+						BranchName: "next",
+						Steps: Steps{
+							{
+								Name: "step2",
+								Function: func(c GoStepsCtx) StepResult {
+									return MarkStateComplete()
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	ctx := NewGoStepsContext().Use(Hooks{
+		OnStepStart: func(c GoStepsCtx, step StepName) {
+			record("start:" + string(step))
+		},
+		OnStepEnd: func(c GoStepsCtx, progress StepProgress) {
+			record(fmt.Sprintf("end:%s:%d", progress.StepName, progress.RunCount))
+		},
+		OnRetry: func(c GoStepsCtx, progress StepProgress, sleep time.Duration) {
+			record(fmt.Sprintf("retry:%s:%s", progress.StepName, sleep))
+		},
+		OnBranchResolved: func(c GoStepsCtx, selection BranchSelection) {
+			record(fmt.Sprintf("branch:%s:%s", selection.StepName, selection.BranchName))
+		},
+		OnChainEnd: func(c GoStepsCtx, report *ExecutionReport) {
+			record("chain:" + string(report.Outcome))
+		},
+	})
+
+	report := root.Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, []string{
+		"start:step1", "retry:step1:1ms", "end:step1:2", "branch:step1:next",
+		"start:step2", "end:step2:1", "chain:StepStateComplete",
+	}, events)
+}
+
+func Test_LoggingMiddleware(t *testing.T) {
+
+	var out bytes.Buffer
+	logger := NewGoStepsLogger(&out, &LoggerOpts{StepLoggingEnabled: true})
+
+	attempts := 0
+	root := NewStepsProcessor(Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				attempts += 1
+				return MarkStateError().WithError(error1)
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 2,
+				RetryAllErrors: true,
+			},
+		},
+	})
+
+	root.Execute(NewGoStepsContext().Use(logger))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"runCount":1`)
+	assert.Contains(t, lines[1], `"runCount":2`)
+	assert.Contains(t, lines[1], `"state":"StepStateError"`)
+}
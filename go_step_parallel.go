@@ -0,0 +1,187 @@
+package gosteps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrDataConflict is the error of a parallel group, in which more than one
+	// step set the same data key, when the ConflictPolicyError policy is used
+	ErrDataConflict = errors.New("data conflict")
+)
+
+// ConflictPolicy type defines how the data of the steps of a
+// parallel group are merged, if more than one step set the same key
+type ConflictPolicy string
+
+const (
+	ConflictPolicyLastWins  ConflictPolicy = "LastWins"  // the data of the step defined last is kept [default]
+	ConflictPolicyFirstWins ConflictPolicy = "FirstWins" // the data of the step defined first is kept
+	ConflictPolicyError     ConflictPolicy = "Error"     // the group errors with ErrDataConflict
+)
+
+// Parallel type defines a group of steps executed concurrently, the group
+// completes when all steps complete, and the data of the steps are merged
+type Parallel struct {
+	Steps          Steps          `json:"steps"`
+	MaxConcurrency int            `json:"maxConcurrency"` // maximum steps run at once, unlimited if not set
+	FailFast       bool           `json:"failFast"`       // cancel the other steps once a step does not complete
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy"`
+}
+
+// execute runs copies of the steps of the parallel group concurrently, each step with a forked context,
+// and aggregates their progress, data, states and errors into the result of the group, the steps are
+// copied so that the retries of the group are isolated from the abandoned attempts that timed out
+func (parallel *Parallel) execute(c GoStepsCtx) StepResult {
+	steps := parallel.Steps.clone()
+
+	ctx, cancel := context.WithCancel(c.Context())
+	defer cancel()
+
+	concurrency := parallel.MaxConcurrency
+	if concurrency <= 0 || concurrency > len(steps) {
+		concurrency = len(steps)
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+	runs := make([]*goStepsRun, len(steps))
+	stores := make([]*ctxStore, len(steps))
+	base := c.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := range steps {
+		stepCtx := c.fork(ctx)
+		runs[i] = stepCtx.run
+		stores[i] = stepCtx.store
+
+		wg.Add(1)
+		go func(steps Steps, stepCtx GoStepsCtx) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if steps.execute(stepCtx) && parallel.FailFast {
+				cancel()
+			}
+		}(steps[i:i+1], stepCtx)
+	}
+
+	wg.Wait()
+
+	changes := make([]GoStepsCtxData, len(runs))
+	for i, run := range runs {
+		c.merge(run)
+		changes[i] = run.changes(stores[i], base)
+	}
+
+	stepResult, deleted := parallel.aggregate(runs, changes)
+	for _, key := range deleted {
+		c.DeleteData(key)
+	}
+
+	return stepResult
+}
+
+// aggregate aggregates the data, states and errors of the runs of the steps into the result of the group,
+// and returns the keys of the data deleted by the steps, changes are the data changed by each step
+//   - the data of all the steps are merged, based on the ConflictPolicy, a deleted key is a change too
+//   - the group completes if all the steps complete or are skipped
+//   - else the group state is failed, error, pending or cancelled, in that order of precedence
+func (parallel *Parallel) aggregate(runs []*goStepsRun, changes []GoStepsCtxData) (StepResult, []string) {
+	data := GoStepsCtxData{}
+	owners := map[string]StepName{}
+	states := map[StepState]bool{}
+
+	var errs ExecutionErrors
+	for i, run := range runs {
+		stepName := parallel.Steps[i].Name
+
+		stepData := changes[i]
+		keys := make([]string, 0, len(stepData))
+		for key := range stepData {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if owner, ok := owners[key]; ok {
+				switch parallel.ConflictPolicy {
+				case ConflictPolicyFirstWins:
+					continue
+				case ConflictPolicyError:
+					errs = append(errs, fmt.Errorf(
+						"%w: key [%s] set by steps [%s] and [%s]", ErrDataConflict, key, owner, stepName,
+					))
+					states[StepStateError] = true
+					continue
+				}
+			}
+
+			data[key] = stepData[key]
+			owners[key] = stepName
+		}
+
+		if report := run.report(); report.Outcome != StepStateComplete {
+			states[report.Outcome] = true
+			errs = append(errs, report.Error)
+		}
+	}
+
+	deleted := []string{}
+	for key, value := range data {
+		if _, ok := value.(deletedData); ok {
+			deleted = append(deleted, key)
+			delete(data, key)
+		}
+	}
+	sort.Strings(deleted)
+
+	for _, state := range []StepState{StepStateFailed, StepStateError, StepStatePending, StepStateCancelled} {
+		if states[state] {
+			return markState(state).WithData(data).WithError(errs.errorOrNil()), deleted
+		}
+	}
+
+	return MarkStateComplete().WithData(data), deleted
+}
+
+// data returns the data set by all the steps of the run, in order of execution
+func (run *goStepsRun) data() GoStepsCtxData {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	data := GoStepsCtxData{}
+	for _, progress := range run.steps {
+		for key, value := range progress.StepResult.StepData {
+			data[key] = value
+		}
+	}
+
+	return data
+}
+
+// deletedData type defines the change of a key deleted by a step, in the changes of the steps
+type deletedData struct{}
+
+// changes returns the data changed by the steps of the run, the data returned by the steps, and
+// the data they set in the store of their forked context, since the base data, keys deleted
+// from the store are changed to deletedData
+func (run *goStepsRun) changes(store *ctxStore, base GoStepsCtxData) GoStepsCtxData {
+	data := run.data()
+
+	changed, deleted := store.diff(base)
+	for key, value := range changed {
+		data[key] = value
+	}
+
+	for _, key := range deleted {
+		data[key] = deletedData{}
+	}
+
+	return data
+}
@@ -0,0 +1,320 @@
+package gosteps
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Parallel(t *testing.T) {
+
+	fetch := func(key string, value int) StepFn {
+		return func(c GoStepsCtx) StepResult {
+			time.Sleep(30 * time.Millisecond)
+			return MarkStateComplete().WithData(map[string]interface{}{
+				key: value + c.GetData("base").(int),
+			})
+		}
+	}
+
+	steps := Steps{
+		{
+			Name: "fetch",
+			Parallel: &Parallel{
+				Steps: Steps{
+					{Name: "fetch1", Function: fetch("r1", 1)},
+					{Name: "fetch2", Function: fetch("r2", 2)},
+					{Name: "fetch3", Function: fetch("r3", 3)},
+				},
+			},
+		},
+		{
+			Name: "merge",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete().WithData(map[string]interface{}{
+					"result": c.GetData("r1").(int) + c.GetData("r2").(int) + c.GetData("r3").(int),
+				})
+			},
+		},
+	}
+
+	ctx := NewGoStepsContext()
+	ctx.SetData("base", 10)
+
+	report := NewStepsProcessor(steps).Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 36, ctx.GetData("result"))
+	assert.Less(t, report.Duration, 90*time.Millisecond)
+
+	stepNames := []StepName{}
+	for _, progress := range report.Steps {
+		stepNames = append(stepNames, progress.StepName)
+	}
+	assert.Equal(t, []StepName{"fetch1", "fetch2", "fetch3", "fetch", "merge"}, stepNames)
+}
+
+func Test_Parallel_MaxConcurrency(t *testing.T) {
+
+	var running, maxRunning int32
+	step := func(c GoStepsCtx) StepResult {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		return MarkStateComplete()
+	}
+
+	steps := Steps{
+		{
+			Name: "group",
+			Parallel: &Parallel{
+				MaxConcurrency: 2,
+				Steps: Steps{
+					{Name: "step1", Function: step},
+					{Name: "step2", Function: step},
+					{Name: "step3", Function: step},
+					{Name: "step4", Function: step},
+				},
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, int32(2), maxRunning)
+}
+
+func Test_Parallel_FailFast(t *testing.T) {
+
+	newSteps := func(failFast bool) Steps {
+		return Steps{
+			{
+				Name: "group",
+				Parallel: &Parallel{
+					FailFast: failFast,
+					Steps: Steps{
+						{
+							Name: "failing",
+							Function: func(c GoStepsCtx) StepResult {
+								return MarkStateError().WithError(error1)
+							},
+						},
+						{
+							Name: "waiting",
+							Function: func(c GoStepsCtx) StepResult {
+								select {
+								case <-c.Done():
+									return MarkStateError().WithError(c.Context().Err())
+								case <-time.After(50 * time.Millisecond):
+									return MarkStateComplete()
+								}
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	ctx := NewGoStepsContext()
+	report := NewStepsProcessor(newSteps(true)).Execute(ctx)
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.True(t, errors.Is(report.Error, error1))
+	assert.Equal(t, StepStateCancelled, ctx.getCtx().GetProgress("waiting").StepResult.StepState)
+
+	ctx = NewGoStepsContext()
+	report = NewStepsProcessor(newSteps(false)).Execute(ctx)
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, StepStateComplete, ctx.getCtx().GetProgress("waiting").StepResult.StepState)
+}
+
+func Test_Parallel_ConflictPolicy(t *testing.T) {
+
+	setKey := func(value string) StepFn {
+		return func(c GoStepsCtx) StepResult {
+			return MarkStateComplete().WithData(map[string]interface{}{
+				"key": value,
+			})
+		}
+	}
+
+	testCases := []struct {
+		ConflictPolicy ConflictPolicy
+		ExpectedState  StepState
+		ExpectedValue  interface{}
+	}{
+		{
+			ConflictPolicy: "",
+			ExpectedState:  StepStateComplete,
+			ExpectedValue:  "second",
+		},
+		{
+			ConflictPolicy: ConflictPolicyFirstWins,
+			ExpectedState:  StepStateComplete,
+			ExpectedValue:  "first",
+		},
+		{
+			ConflictPolicy: ConflictPolicyError,
+			ExpectedState:  StepStateError,
+			ExpectedValue:  "first",
+		},
+	}
+
+	for _, tc := range testCases {
+		steps := Steps{
+			{
+				Name: "group",
+				Parallel: &Parallel{
+					ConflictPolicy: tc.ConflictPolicy,
+					Steps: Steps{
+						{Name: "first", Function: setKey("first")},
+						{Name: "second", Function: setKey("second")},
+					},
+				},
+			},
+		}
+
+		ctx := NewGoStepsContext()
+		report := NewStepsProcessor(steps).Execute(ctx)
+
+		assert.Equal(t, tc.ExpectedState, report.Outcome)
+		assert.Equal(t, tc.ExpectedValue, ctx.GetData("key"))
+
+		if tc.ConflictPolicy == ConflictPolicyError {
+			assert.True(t, errors.Is(report.Error, ErrDataConflict))
+		}
+	}
+}
+
+func Test_Parallel_SetData(t *testing.T) {
+
+	testCases := []struct {
+		ConflictPolicy ConflictPolicy
+		ExpectedState  StepState
+		ExpectedData   GoStepsCtxData
+	}{
+		{
+			ConflictPolicy: "",
+			ExpectedState:  StepStateComplete,
+			ExpectedData:   GoStepsCtxData{"counted": 2, "key": "second"},
+		},
+		{
+			ConflictPolicy: ConflictPolicyFirstWins,
+			ExpectedState:  StepStateComplete,
+			ExpectedData:   GoStepsCtxData{"counted": 2},
+		},
+		{
+			ConflictPolicy: ConflictPolicyError,
+			ExpectedState:  StepStateError,
+			ExpectedData:   GoStepsCtxData{"counted": 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		steps := Steps{
+			{
+				Name: "group",
+				Parallel: &Parallel{
+					ConflictPolicy: tc.ConflictPolicy,
+					Steps: Steps{
+						{
+							Name: "first",
+							Function: func(c GoStepsCtx) StepResult {
+								c.UpdateData("counted", func(value interface{}) interface{} { return value.(int) + 1 })
+								c.DeleteData("key")
+								return MarkStateComplete()
+							},
+						},
+						{
+							Name: "second",
+							Function: func(c GoStepsCtx) StepResult {
+								c.SetData("key", "second")
+								return MarkStateComplete()
+							},
+						},
+					},
+				},
+			},
+		}
+
+		ctx := NewGoStepsContext()
+		ctx.WithData(map[string]interface{}{"counted": 1, "key": "initial"})
+		report := NewStepsProcessor(steps).Execute(ctx)
+
+		// the data set in the forked contexts of the steps is merged, a deleted key is a change
+		assert.Equal(t, tc.ExpectedState, report.Outcome)
+		assert.Equal(t, tc.ExpectedData, ctx.Snapshot())
+
+		if tc.ConflictPolicy == ConflictPolicyError {
+			assert.True(t, errors.Is(report.Error, ErrDataConflict))
+		}
+	}
+}
+
+func Test_Parallel_TimeoutRetry(t *testing.T) {
+
+	var attempts int32
+	abandoned := make(chan struct{})
+
+	steps := Steps{
+		{
+			Name: "group",
+			Parallel: &Parallel{
+				Steps: Steps{
+					{
+						Name: "wait",
+						Function: func(c GoStepsCtx) StepResult {
+							if atomic.AddInt32(&attempts, 1) == 1 {
+								defer close(abandoned)
+								time.Sleep(50 * time.Millisecond)
+							}
+
+							return MarkStateComplete()
+						},
+					},
+				},
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 2,
+				Timeout:        20 * time.Millisecond,
+				ErrorsToRetry:  []error{ErrStepTimeout},
+			},
+		},
+		{
+			Name: "next",
+			Function: func(c GoStepsCtx) StepResult {
+				<-abandoned
+				time.Sleep(20 * time.Millisecond)
+
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+
+	// the abandoned attempt of the group runs its own copy of the steps, and does not change the report
+	waits := 0
+	for _, progress := range report.Steps {
+		if progress.StepName == "wait" {
+			waits += 1
+		}
+	}
+	assert.Equal(t, 1, waits)
+}
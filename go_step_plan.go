@@ -0,0 +1,413 @@
+package gosteps
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// UnreachableReason type defines why a branch is not reached in the execution plan
+type UnreachableReason string
+
+const (
+	UnreachableNotSelected UnreachableReason = "NotSelected" // the resolver selects another branch, with the data of the plan
+	UnreachableNotReached  UnreachableReason = "NotReached"  // the step-chain stops before the branches of the step are resolved
+	UnreachableDuplicate   UnreachableReason = "Duplicate"   // a previous branch of the step has the same name, the branch is never selected
+)
+
+// PlanOpts type defines the options of the execution plan of a step-chain
+type PlanOpts struct {
+	Data       GoStepsCtxData          // data set in the context before planning, in addition to the data of the context
+	Stubs      map[StepName]StepResult // results of the steps, used instead of the step functions, StepStateComplete if not set
+	TimeBudget time.Duration           // time budget of each step, steps whose retries could exceed it are flagged, not checked if not set
+}
+
+// PlannedStep type defines a step of the execution plan, with the attempts and time the step could consume
+type PlannedStep struct {
+	StepName       StepName      `json:"stepName"`
+	StepState      StepState     `json:"stepState,omitempty"`   // state of the stub result, empty if the step has no function
+	MaxRunAttempts int           `json:"maxRunAttempts"`        // maximum attempts of the step
+	SleepBudget    time.Duration `json:"sleepBudget"`           // longest total sleep between the attempts of the step
+	MaxDuration    time.Duration `json:"maxDuration,omitempty"` // longest duration of the step, 0 if the attempts have no timeout
+	OverBudget     bool          `json:"overBudget,omitempty"`  // the step could exceed the time budget
+}
+
+// UnreachableBranch type defines a branch that is not executed in the execution plan
+type UnreachableBranch struct {
+	StepName   StepName          `json:"stepName"`
+	BranchName BranchName        `json:"branchName"`
+	Reason     UnreachableReason `json:"reason"`
+}
+
+// ExecutionPlan type defines the plan of a step-chain execution, see Branch.Plan
+type ExecutionPlan struct {
+	Outcome     StepState           `json:"outcome"`         // final state of the step-chain, with the stub results
+	Steps       []PlannedStep       `json:"steps"`           // steps that would be executed, in order of execution
+	BranchPath  []BranchSelection   `json:"branchPath"`      // branches selected by the resolvers
+	Unreachable []UnreachableBranch `json:"unreachable"`     // branches that would not be executed
+	OverBudget  []StepName          `json:"overBudget"`      // steps that could exceed the time budget
+	Error       error               `json:"error,omitempty"` // error of resolving a branch, if any
+}
+
+// planner type defines the state of the planning of a step-chain
+type planner struct {
+	opts      PlanOpts
+	plan      *ExecutionPlan
+	namespace string           // namespace of the sub-workflow being planned, as in the execution
+	workflows map[*Branch]bool // branches being planned, the root branch and the enclosing sub-workflows
+}
+
+// Plan returns the execution plan of the step-chain, without executing the step functions. The
+// steps are walked in order, the stub results of PlanOpts.Stubs, or complete results, are used
+// instead of the step functions, and the resolvers are called with the data of the context, the
+// data of PlanOpts.Data, the step args and the data of the stub results. The data of the context
+// is not changed. Resolvers and conditions are called as in an execution, and must not have side
+// effects, steps skipped by their conditions are planned as skipped, without their branches. The
+// steps of loops are planned once, the steps of sub-workflows are planned with namespaced names, as
+// are their stubs, and the control results of the stubs, example GotoStep, are ignored
+func (branch *Branch) Plan(c GoStepsContext, opts PlanOpts) *ExecutionPlan {
+	planCtx := c.getCtx().fork(c.Context())
+	planCtx.WithData(opts.Data)
+
+	p := &planner{
+		opts:      opts,
+		workflows: map[*Branch]bool{branch: true},
+		plan: &ExecutionPlan{
+			Outcome:     StepStateComplete,
+			Steps:       []PlannedStep{},
+			BranchPath:  []BranchSelection{},
+			Unreachable: []UnreachableBranch{},
+			OverBudget:  []StepName{},
+		},
+	}
+
+	if state := p.planSteps(planCtx, branch.Steps); state != "" {
+		p.plan.Outcome = state
+	}
+
+	return p.plan
+}
+
+// planSteps plans the steps in order, returns the state of
+// the step terminating the step-chain, empty if none does
+func (p *planner) planSteps(c GoStepsCtx, steps Steps) StepState {
+	for i := range steps {
+		step := &steps[i]
+		_, skipped := step.skipReason(c)
+		index, stepResult := p.planStep(c, step, skipped)
+
+		var branch *Branch
+		resolved := false
+		if step.Branches != nil && !skipped && (stepResult == nil || stepResult.isDone()) {
+			var err error
+
+			c.SetCurrentStep(step.Name)
+			branch, _, err = step.Branches.resolve(c, step.Name)
+			resolved = true
+
+			if err != nil {
+				p.plan.Error = err
+				p.plan.Steps[index].StepState = StepStateError
+
+				errorResult := MarkStateError().WithError(err)
+				stepResult = &errorResult
+			}
+		}
+
+		p.flagBranches(step, branch, resolved)
+
+		if stepResult != nil && !stepResult.isDone() {
+			p.flagNotReached(steps[i+1:])
+			return stepResult.StepState
+		}
+
+		if branch != nil {
+			p.plan.BranchPath = append(p.plan.BranchPath, BranchSelection{
+				StepName:   p.name(step),
+				BranchName: branch.BranchName,
+			})
+
+			if state := p.planSteps(c, branch.Steps); state != "" {
+				p.flagNotReached(steps[i+1:])
+				return state
+			}
+		}
+	}
+
+	return ""
+}
+
+// planStep adds the step to the plan, and sets the data of the stub result of the step in the context,
+// returns the index of the step in the plan, and the stub result, nil if the step has no function, or
+// a skipped result if the step is skipped by its conditions
+func (p *planner) planStep(c GoStepsCtx, step *Step, skipped bool) (int, *StepResult) {
+	planned := step.StepOpts.plannedStep(p.name(step), p.opts.TimeBudget)
+	if planned.OverBudget {
+		p.plan.OverBudget = append(p.plan.OverBudget, planned.StepName)
+	}
+
+	index := len(p.plan.Steps)
+	p.plan.Steps = append(p.plan.Steps, planned)
+
+	if skipped {
+		stepResult := MarkStateSkipped()
+		c.SetProgress(step.Name, stepResult)
+		p.plan.Steps[index].StepState = stepResult.StepState
+
+		return index, &stepResult
+	}
+
+	if step.stepFn() == nil {
+		return index, nil
+	}
+
+	c.SetCurrentStep(step.Name)
+	c.WithData(step.StepArgs)
+
+	stepResult := MarkStateComplete()
+	if step.Parallel != nil {
+		stepResult = markState(p.planParallel(c, step.Parallel))
+	}
+
+	if step.Loop != nil {
+		if state := p.planSteps(c, step.Loop.Steps); state != "" {
+			stepResult = markState(state)
+		}
+	}
+
+	if step.SubWorkflow != nil && step.SubWorkflow.Branch != nil {
+		stepResult = p.planSubWorkflow(c, step)
+	}
+
+	if stub, ok := p.opts.Stubs[p.name(step)]; ok {
+		stepResult = stub
+	}
+
+	c.WithData(stepResult.StepData)
+	c.SetProgress(step.Name, stepResult)
+	p.plan.Steps[index].StepState = stepResult.StepState
+
+	return index, &stepResult
+}
+
+// planSubWorkflow plans the steps of the sub-workflow, with the inputs as data, in the namespace of
+// the sub-workflow, returns the state of the sub-workflow, with the outputs as data if it completes.
+// A sub-workflow running one of its enclosing branches is not planned again
+func (p *planner) planSubWorkflow(c GoStepsCtx, step *Step) StepResult {
+	subWorkflow := step.SubWorkflow
+	branch := subWorkflow.Branch
+	if p.workflows[branch] {
+		return MarkStateComplete()
+	}
+
+	subCtx := c.fork(c.Context())
+	subCtx.store = newCtxStore(mapData(c.Snapshot(), subWorkflow.Inputs))
+
+	namespace := p.namespace
+	p.namespace = fmt.Sprintf("%s%s/%s/", namespace, step.Name, branch.BranchName)
+	p.workflows[branch] = true
+
+	state := p.planSteps(subCtx, branch.Steps)
+
+	delete(p.workflows, branch)
+	p.namespace = namespace
+
+	if state != "" {
+		return markState(state)
+	}
+
+	return MarkStateComplete().WithData(mapData(subCtx.Snapshot(), subWorkflow.Outputs))
+}
+
+// name returns the name of the step, prefixed with the namespace of the sub-workflow being planned
+func (p *planner) name(step *Step) StepName {
+	return StepName(p.namespace) + step.Name
+}
+
+// planParallel plans the steps of the parallel group, returns the state of the group, see Parallel.aggregate
+func (p *planner) planParallel(c GoStepsCtx, parallel *Parallel) StepState {
+	states := map[StepState]bool{}
+	for i := range parallel.Steps {
+		if state := p.planSteps(c, parallel.Steps[i:i+1]); state != "" {
+			states[state] = true
+		}
+	}
+
+	for _, state := range []StepState{StepStateFailed, StepStateError, StepStatePending, StepStateCancelled} {
+		if states[state] {
+			return state
+		}
+	}
+
+	return StepStateComplete
+}
+
+// flagBranches flags the branches of the step that are not selected, and the branches within them
+func (p *planner) flagBranches(step *Step, selected *Branch, resolved bool) {
+	if step.Branches == nil {
+		return
+	}
+
+	seen := map[BranchName]bool{}
+	for i := range step.Branches.Branches {
+		branch := &step.Branches.Branches[i]
+
+		var reason UnreachableReason
+		switch {
+		case seen[branch.BranchName]:
+			reason = UnreachableDuplicate
+		case !resolved:
+			reason = UnreachableNotReached
+		case selected == nil || selected.BranchName != branch.BranchName:
+			reason = UnreachableNotSelected
+		}
+
+		seen[branch.BranchName] = true
+		if reason == "" {
+			continue
+		}
+
+		p.plan.Unreachable = append(p.plan.Unreachable, UnreachableBranch{
+			StepName:   p.name(step),
+			BranchName: branch.BranchName,
+			Reason:     reason,
+		})
+
+		p.flagNotReached(branch.Steps)
+	}
+}
+
+// flagNotReached flags the branches of the steps, and of their parallel steps and loops, as not reached
+func (p *planner) flagNotReached(steps Steps) {
+	for i := range steps {
+		if steps[i].Parallel != nil {
+			p.flagNotReached(steps[i].Parallel.Steps)
+		}
+
+		if steps[i].Loop != nil {
+			p.flagNotReached(steps[i].Loop.Steps)
+		}
+
+		p.flagBranches(&steps[i], nil, false)
+	}
+}
+
+// plannedStep returns the planned step, with the maximum attempts, sleep
+// and duration of the step, and if they could exceed the time budget
+func (stepOpts StepOpts) plannedStep(stepName StepName, timeBudget time.Duration) PlannedStep {
+	attempts := stepOpts.MaxRunAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	sleepBudget := stepOpts.sleepBudget(attempts)
+
+	var maxDuration time.Duration
+	if stepOpts.Timeout > 0 {
+		maxDuration = durationOf(float64(stepOpts.Timeout)*float64(attempts) + float64(sleepBudget))
+	}
+
+	// the total timeout bounds the attempts and the sleeps between them
+	if stepOpts.TotalTimeout > 0 {
+		if maxDuration == 0 || maxDuration > stepOpts.TotalTimeout {
+			maxDuration = stepOpts.TotalTimeout
+		}
+
+		if sleepBudget > stepOpts.TotalTimeout {
+			sleepBudget = stepOpts.TotalTimeout
+		}
+	}
+
+	return PlannedStep{
+		StepName:       stepName,
+		MaxRunAttempts: attempts,
+		SleepBudget:    sleepBudget,
+		MaxDuration:    maxDuration,
+		OverBudget:     timeBudget > 0 && (sleepBudget > timeBudget || maxDuration > timeBudget),
+	}
+}
+
+// sleepBudget returns the longest total sleep between the attempts of the step, jittered
+// backoffs are counted with their longest delay, and the delays are capped by MaxRetrySleep.
+// Once the delays stop growing, the sleeps of the remaining retries are counted at once
+func (stepOpts StepOpts) sleepBudget(attempts int) time.Duration {
+	backoff := stepOpts.Backoff
+	if backoff == nil {
+		backoff = ConstantBackoff{Interval: stepOpts.RetrySleep}
+	}
+
+	retries := attempts - 1
+	if linear, ok := backoff.(LinearBackoff); ok && linear.Increment > 0 {
+		return stepOpts.linearSleepBudget(linear, retries)
+	}
+
+	var budget, delay time.Duration
+	for attempt := 1; attempt <= retries && budget < math.MaxInt64; attempt++ {
+		previous := delay
+
+		delay = maxDelay(backoff, attempt, previous)
+		if stepOpts.MaxRetrySleep > 0 && delay > stepOpts.MaxRetrySleep {
+			delay = stepOpts.MaxRetrySleep
+		}
+
+		budget = durationOf(float64(budget) + float64(delay))
+
+		if stepOpts.plateau(backoff, attempt, previous, delay) {
+			return durationOf(float64(budget) + float64(delay)*float64(retries-attempt))
+		}
+	}
+
+	return budget
+}
+
+// plateau returns true if the delays of the backoff stop growing at the delay, the delay is
+// capped by MaxRetrySleep, the backoff is constant, or the delay is the previous delay. The
+// exponential delays are not compared, as small delays can be rounded to the previous one
+func (stepOpts StepOpts) plateau(backoff Backoff, attempt int, previous, delay time.Duration) bool {
+	if stepOpts.MaxRetrySleep > 0 && delay == stepOpts.MaxRetrySleep {
+		return true
+	}
+
+	switch b := backoff.(type) {
+	case ConstantBackoff:
+		return true
+	case ExponentialBackoff:
+		return b.Multiplier == 1 || delay == 0
+	case ExponentialJitterBackoff:
+		return b.Multiplier == 1 || delay == 0
+	default:
+		return attempt > 1 && delay == previous
+	}
+}
+
+// linearSleepBudget returns the sleep budget of the retries of a growing linear backoff, the
+// sum of the delays below MaxRetrySleep, an arithmetic series, and of the delays capped by it
+func (stepOpts StepOpts) linearSleepBudget(backoff LinearBackoff, retries int) time.Duration {
+	initial, increment := float64(backoff.Initial), float64(backoff.Increment)
+	maxSleep := float64(stepOpts.MaxRetrySleep)
+
+	n := float64(retries)
+	uncapped := n
+	if maxSleep > 0 {
+		uncapped = math.Min(n, math.Max(0, math.Floor((maxSleep-initial)/increment)+1))
+	}
+
+	budget := uncapped*initial + increment*uncapped*(uncapped-1)/2 + (n-uncapped)*maxSleep
+	return durationOf(budget)
+}
+
+// maxDelay returns the longest delay of the backoff before the next attempt
+func maxDelay(backoff Backoff, attempt int, previousDelay time.Duration) time.Duration {
+	switch b := backoff.(type) {
+	case ExponentialJitterBackoff:
+		return exponentialDelay(b.Initial, b.Multiplier, attempt)
+	case DecorrelatedJitterBackoff:
+		if previousDelay < b.Base {
+			previousDelay = b.Base
+		}
+
+		return durationOf(float64(previousDelay) * 3)
+	default:
+		return backoff.Delay(attempt, previousDelay)
+	}
+}
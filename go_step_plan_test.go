@@ -0,0 +1,294 @@
+package gosteps
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Plan(t *testing.T) {
+
+	executed := false
+	noop := func(c GoStepsCtx) StepResult {
+		executed = true
+		return MarkStateComplete()
+	}
+
+	steps := Steps{
+		{
+			Name:     "fetch",
+			Function: noop,
+			StepOpts: StepOpts{
+				MaxRunAttempts: 4,
+				Backoff:        ExponentialBackoff{Initial: time.Second, Multiplier: 2},
+				Timeout:        10 * time.Second,
+			},
+			Branches: &Branches{
+				Resolver: func(c GoStepsCtx) BranchName {
+					if c.GetData("size").(int) > 100 {
+						return "bulk"
+					}
+
+					return "single"
+				},
+				Branches: []Branch{
+					{
+						BranchName: "single",
+						Steps: Steps{
+							{Name: "single.process", Function: noop},
+						},
+					},
+					{
+						BranchName: "bulk",
+						Steps: Steps{
+							{
+								Name:     "bulk.process",
+								Function: noop,
+								StepOpts: StepOpts{
+									MaxRunAttempts: 3,
+									RetrySleep:     time.Minute,
+								},
+								Branches: &Branches{
+									Resolver: func(c GoStepsCtx) BranchName { return "nested" },
+									Branches: []Branch{{BranchName: "nested"}},
+								},
+							},
+						},
+					},
+					{
+						BranchName: "single",
+					},
+				},
+			},
+		},
+		{
+			Name:     "store",
+			Function: noop,
+		},
+	}
+
+	branch := NewStepsProcessor(steps)
+
+	c := NewGoStepsContext()
+	plan := branch.Plan(c, PlanOpts{
+		Data:       GoStepsCtxData{"size": 10},
+		TimeBudget: 30 * time.Second,
+	})
+
+	assert.False(t, executed)
+	assert.Nil(t, c.GetData("size"))
+
+	assert.Equal(t, StepStateComplete, plan.Outcome)
+	assert.Equal(t, []PlannedStep{
+		{
+			StepName:       "fetch",
+			StepState:      StepStateComplete,
+			MaxRunAttempts: 4,
+			SleepBudget:    7 * time.Second,
+			MaxDuration:    47 * time.Second,
+			OverBudget:     true,
+		},
+		{StepName: "single.process", StepState: StepStateComplete, MaxRunAttempts: 1},
+		{StepName: "store", StepState: StepStateComplete, MaxRunAttempts: 1},
+	}, plan.Steps)
+	assert.Equal(t, []BranchSelection{{StepName: "fetch", BranchName: "single"}}, plan.BranchPath)
+	assert.Equal(t, []StepName{"fetch"}, plan.OverBudget)
+	assert.Equal(t, []UnreachableBranch{
+		{StepName: "fetch", BranchName: "bulk", Reason: UnreachableNotSelected},
+		{StepName: "bulk.process", BranchName: "nested", Reason: UnreachableNotReached},
+		{StepName: "fetch", BranchName: "single", Reason: UnreachableDuplicate},
+	}, plan.Unreachable)
+
+	// the stub results stop the step-chain, and their data is used by the resolvers
+	plan = branch.Plan(c, PlanOpts{
+		Data: GoStepsCtxData{"size": 10},
+		Stubs: map[StepName]StepResult{
+			"fetch":        MarkStateComplete().WithData(map[string]interface{}{"size": 500}),
+			"bulk.process": MarkStateError().WithError(error1),
+		},
+	})
+
+	assert.Equal(t, StepStateError, plan.Outcome)
+	assert.Len(t, plan.Steps, 2)
+	assert.Equal(t, PlannedStep{
+		StepName:       "bulk.process",
+		StepState:      StepStateError,
+		MaxRunAttempts: 3,
+		SleepBudget:    2 * time.Minute,
+	}, plan.Steps[1])
+	assert.Equal(t, []BranchSelection{{StepName: "fetch", BranchName: "bulk"}}, plan.BranchPath)
+	assert.Empty(t, plan.OverBudget)
+	assert.Equal(t, []UnreachableBranch{
+		{StepName: "fetch", BranchName: "single", Reason: UnreachableNotSelected},
+		{StepName: "fetch", BranchName: "single", Reason: UnreachableDuplicate},
+		{StepName: "bulk.process", BranchName: "nested", Reason: UnreachableNotReached},
+	}, plan.Unreachable)
+	assert.False(t, executed)
+}
+
+func Test_Plan_UnresolvedBranch(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "route",
+			Branches: &Branches{
+				Resolver:     func(c GoStepsCtx) BranchName { return "unknown" },
+				OnUnresolved: OnUnresolvedFail,
+				Branches:     []Branch{{BranchName: "known"}},
+			},
+		},
+	}
+
+	plan := NewStepsProcessor(steps).Plan(NewGoStepsContext(), PlanOpts{})
+
+	var unresolvedBranchError *UnresolvedBranchError
+	assert.True(t, errors.As(plan.Error, &unresolvedBranchError))
+	assert.Equal(t, StepStateError, plan.Outcome)
+	assert.Equal(t, []UnreachableBranch{
+		{StepName: "route", BranchName: "known", Reason: UnreachableNotSelected},
+	}, plan.Unreachable)
+}
+
+func Test_Plan_MaxMaxAttempts(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name:     "poll",
+			Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() },
+			StepOpts: StepOpts{
+				MaxRunAttempts: MaxMaxAttempts,
+				Backoff:        ExponentialBackoff{Initial: time.Second},
+				MaxRetrySleep:  time.Minute,
+			},
+		},
+		{
+			Name:     "custom",
+			Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() },
+			StepOpts: StepOpts{
+				MaxRunAttempts: MaxMaxAttempts,
+				Backoff:        constantDelay(0),
+			},
+		},
+		{
+			Name:     "decorrelated",
+			Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() },
+			StepOpts: StepOpts{
+				MaxRunAttempts: MaxMaxAttempts,
+				Backoff:        DecorrelatedJitterBackoff{},
+			},
+		},
+	}
+
+	plan := NewStepsProcessor(steps).Plan(NewGoStepsContext(), PlanOpts{TimeBudget: time.Hour})
+
+	// the sleeps are counted at once when capped by the max retry sleep, and saturate
+	assert.Equal(t, MaxMaxAttempts, plan.Steps[0].MaxRunAttempts)
+	assert.Equal(t, time.Duration(math.MaxInt64), plan.Steps[0].SleepBudget)
+	assert.True(t, plan.Steps[0].OverBudget)
+	assert.Equal(t, []StepName{"poll"}, plan.OverBudget)
+
+	// the delays of the backoffs stop growing at 0, the rest of the retries do not sleep
+	assert.Equal(t, time.Duration(0), plan.Steps[1].SleepBudget)
+	assert.Equal(t, time.Duration(0), plan.Steps[2].SleepBudget)
+}
+
+// constantDelay is a custom Backoff returning the same delay for all the retries
+type constantDelay time.Duration
+
+func (delay constantDelay) Delay(attempt int, previousDelay time.Duration) time.Duration {
+	return time.Duration(delay)
+}
+
+func Test_SleepBudget(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		stepOpts StepOpts
+		expected time.Duration
+	}{
+		{
+			name:     "single attempt",
+			stepOpts: StepOpts{RetrySleep: time.Second},
+			expected: 0,
+		},
+		{
+			name:     "retry sleep",
+			stepOpts: StepOpts{MaxRunAttempts: 3, RetrySleep: time.Second},
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "exponential jitter, longest delays",
+			stepOpts: StepOpts{MaxRunAttempts: 4, Backoff: ExponentialJitterBackoff{Initial: time.Second}},
+			expected: 7 * time.Second,
+		},
+		{
+			name:     "decorrelated jitter, capped by max retry sleep",
+			stepOpts: StepOpts{MaxRunAttempts: 4, Backoff: DecorrelatedJitterBackoff{Base: time.Second}, MaxRetrySleep: 5 * time.Second},
+			expected: 13 * time.Second,
+		},
+		{
+			name:     "linear, capped by max retry sleep",
+			stepOpts: StepOpts{MaxRunAttempts: 6, Backoff: LinearBackoff{Initial: time.Second, Increment: time.Second}, MaxRetrySleep: 3 * time.Second},
+			expected: 12 * time.Second,
+		},
+		{
+			name:     "linear",
+			stepOpts: StepOpts{MaxRunAttempts: 4, Backoff: LinearBackoff{Initial: time.Second, Increment: 2 * time.Second}},
+			expected: 9 * time.Second,
+		},
+		{
+			name:     "exponential, decreasing",
+			stepOpts: StepOpts{MaxRunAttempts: MaxMaxAttempts, Backoff: ExponentialBackoff{Initial: 4, Multiplier: 0.5}},
+			expected: 7,
+		},
+		{
+			name:     "many attempts, retry sleep",
+			stepOpts: StepOpts{MaxRunAttempts: 1e12 + 1, RetrySleep: time.Millisecond},
+			expected: 1e12 * time.Millisecond,
+		},
+		{
+			name:     "max attempts, retry sleep",
+			stepOpts: StepOpts{MaxRunAttempts: MaxMaxAttempts, RetrySleep: time.Second},
+			expected: time.Duration(math.MaxInt64),
+		},
+		{
+			name:     "max attempts, custom constant backoff",
+			stepOpts: StepOpts{MaxRunAttempts: MaxMaxAttempts, Backoff: constantDelay(0)},
+			expected: 0,
+		},
+		{
+			name:     "max attempts, decorrelated jitter without base",
+			stepOpts: StepOpts{MaxRunAttempts: MaxMaxAttempts, Backoff: DecorrelatedJitterBackoff{}},
+			expected: 0,
+		},
+		{
+			name:     "max attempts, decorrelated jitter",
+			stepOpts: StepOpts{MaxRunAttempts: MaxMaxAttempts, Backoff: DecorrelatedJitterBackoff{Base: time.Second}},
+			expected: time.Duration(math.MaxInt64),
+		},
+		{
+			name:     "many attempts, custom constant backoff",
+			stepOpts: StepOpts{MaxRunAttempts: 1e12 + 1, Backoff: constantDelay(time.Millisecond)},
+			expected: 1e12 * time.Millisecond,
+		},
+		{
+			name:     "max attempts, linear capped by max retry sleep",
+			stepOpts: StepOpts{MaxRunAttempts: MaxMaxAttempts, Backoff: LinearBackoff{Increment: time.Nanosecond}, MaxRetrySleep: time.Hour},
+			expected: time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := tc.stepOpts.MaxRunAttempts
+			if attempts < 1 {
+				attempts = 1
+			}
+
+			assert.Equal(t, tc.expected, tc.stepOpts.sleepBudget(attempts))
+		})
+	}
+}
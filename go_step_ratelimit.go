@@ -0,0 +1,194 @@
+package gosteps
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter type defines a limiter of the attempts of the steps, Wait is called before each attempt
+// of a step, and blocks until the attempt is allowed, or returns the error of the context if it is
+// done before. Set the rate limiter of a step with StepOpts.RateLimiter, of the steps of a branch with
+// BranchOpts.RateLimiter, or of all the steps run with a context with ctx.Use(limiter). A rate limiter
+// can be shared by many steps, and by concurrent step-chain executions, and must be safe for concurrent use
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// Releaser type defines a RateLimiter that can give back the token taken by a Wait. An attempt waits
+// on its rate limiters in order, if a rate limiter returns an error, the attempt does not run, and the
+// rate limiters waited on before it are released, if they implement Releaser
+type Releaser interface {
+	Release()
+}
+
+// TokenBucket type defines a token bucket RateLimiter, the bucket holds up to burst tokens, refilled
+// at rate tokens per second, each attempt takes a token, or waits for the next one. Create with
+// NewTokenBucket, a TokenBucket is safe for concurrent use, waiting attempts are served in order
+type TokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	clock  Clock
+}
+
+// NewTokenBucket returns a token bucket allowing rate attempts per second, and bursts of up to
+// burst attempts, the bucket starts full. A rate of 0 or less does not limit the attempts
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		clock:  realClock{},
+	}
+}
+
+// WithClock sets the clock of the bucket, used to refill the tokens and to wait for them,
+// example the gostepstest.FakeClock in tests, the real time is used if not set
+func (bucket *TokenBucket) WithClock(clock Clock) *TokenBucket {
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.clock = clock
+	bucket.last = clock.Now()
+
+	return bucket
+}
+
+// Wait takes a token from the bucket, waiting until a token is available, returns the error
+// of the context if it is done before, the token is then given back to the bucket
+func (bucket *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	wait, clock := bucket.reserve()
+	if wait <= 0 {
+		return nil
+	}
+
+	if err := clock.Sleep(ctx, wait); err != nil {
+		bucket.Release()
+		return err
+	}
+
+	return nil
+}
+
+// Release gives back a token taken by Wait, for an attempt that did not run
+func (bucket *TokenBucket) Release() {
+	if bucket.rate <= 0 {
+		return
+	}
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.refill()
+	bucket.tokens += 1
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+// reserve takes a token from the bucket, the tokens can go below zero for the waiting attempts,
+// returns the time until the token is available, 0 if it is available now, and the clock to wait with
+func (bucket *TokenBucket) reserve() (time.Duration, Clock) {
+	if bucket.rate <= 0 {
+		return 0, nil
+	}
+
+	bucket.mutex.Lock()
+	defer bucket.mutex.Unlock()
+
+	bucket.refill()
+	bucket.tokens -= 1
+	if bucket.tokens >= 0 {
+		return 0, bucket.clock
+	}
+
+	return durationOf(-bucket.tokens / bucket.rate * float64(time.Second)), bucket.clock
+}
+
+// refill adds the tokens refilled since the last refill, up to the burst, the bucket must be locked
+func (bucket *TokenBucket) refill() {
+	now := bucket.clock.Now()
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+}
+
+// withRateLimiter returns a copy of the context with the rate limiter added after the existing
+// ones, if set, the rate limiters of the context are not modified
+func (ctx GoStepsCtx) withRateLimiter(limiter RateLimiter) GoStepsCtx {
+	if limiter == nil {
+		return ctx
+	}
+
+	combined := make([]RateLimiter, 0, len(ctx.rateLimiters)+1)
+	combined = append(combined, ctx.rateLimiters...)
+	ctx.rateLimiters = append(combined, limiter)
+
+	return ctx
+}
+
+// rateLimiters returns the rate limiters of the attempts of the step, the rate limiters of the
+// context and branches apply to the steps running a function, not to the parallel groups, loops
+// and sub-workflows, whose steps are limited instead, the rate limiter of the step always applies
+func (step *Step) rateLimiters(c *GoStepsCtx) []RateLimiter {
+	var limiters []RateLimiter
+	if step.Parallel == nil && step.Loop == nil && step.SubWorkflow == nil {
+		limiters = append(limiters, c.rateLimiters...)
+	}
+
+	if step.StepOpts.RateLimiter != nil {
+		limiters = append(limiters, step.StepOpts.RateLimiter)
+	}
+
+	return limiters
+}
+
+// waitRateLimit waits on the rate limiters of the step before an attempt, the time waiting is
+// added to the wait duration of the step, returns the error of the context if it is done before
+func (step *Step) waitRateLimit(c *GoStepsCtx) error {
+	if step.stepFn() == nil {
+		return nil
+	}
+
+	limiters := step.rateLimiters(c)
+	if len(limiters) == 0 {
+		return nil
+	}
+
+	startedAt := time.Now()
+	defer func() {
+		step.stepRunProgress.waitDuration += time.Since(startedAt)
+	}()
+
+	for i, limiter := range limiters {
+		if err := limiter.Wait(c.Context()); err != nil {
+			release(limiters[:i])
+			return err
+		}
+	}
+
+	return nil
+}
+
+// release gives back the tokens taken from the rate limiters, for an attempt that does not run
+func release(limiters []RateLimiter) {
+	for _, limiter := range limiters {
+		if releaser, ok := limiter.(Releaser); ok {
+			releaser.Release()
+		}
+	}
+}
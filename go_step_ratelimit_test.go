@@ -0,0 +1,218 @@
+package gosteps
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// delayLimiter waits for the delay, and records the waits of the rate limiters returned by named
+type delayLimiter struct {
+	mutex sync.Mutex
+	waits []string
+	delay time.Duration
+}
+
+func (limiter *delayLimiter) Wait(ctx context.Context) error {
+	select {
+	case <-time.After(limiter.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (limiter *delayLimiter) named(name string) RateLimiter {
+	return rateLimiterFunc(func(ctx context.Context) error {
+		limiter.mutex.Lock()
+		limiter.waits = append(limiter.waits, name)
+		limiter.mutex.Unlock()
+
+		return limiter.Wait(ctx)
+	})
+}
+
+type rateLimiterFunc func(ctx context.Context) error
+
+func (fn rateLimiterFunc) Wait(ctx context.Context) error {
+	return fn(ctx)
+}
+
+func Test_TokenBucket(t *testing.T) {
+
+	bucket := NewTokenBucket(20, 2)
+	ctx := context.Background()
+
+	startedAt := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, bucket.Wait(ctx))
+	}
+
+	// the burst is taken at once, the next 2 tokens are refilled every 50ms
+	elapsed := time.Since(startedAt)
+	assert.GreaterOrEqual(t, elapsed, 90*time.Millisecond)
+	assert.Less(t, elapsed, 500*time.Millisecond)
+}
+
+func Test_TokenBucket_Cancelled(t *testing.T) {
+
+	bucket := NewTokenBucket(1, 1)
+	assert.NoError(t, bucket.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := bucket.Wait(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// the token of the cancelled wait is given back
+	wait, _ := bucket.reserve()
+	assert.Less(t, wait, 1100*time.Millisecond)
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	assert.True(t, errors.Is(bucket.Wait(cancelled), context.Canceled))
+}
+
+func Test_TokenBucket_Clock(t *testing.T) {
+
+	clock := &recordingClock{}
+	bucket := NewTokenBucket(10, 1).WithClock(clock)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, bucket.Wait(context.Background()))
+	}
+
+	// the bucket waits for the tokens with the clock, and is refilled by its time
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, clock.sleeps)
+}
+
+func Test_RateLimiter_Levels(t *testing.T) {
+
+	limiter := &delayLimiter{}
+
+	attempts := 0
+	steps := Steps{
+		{
+			Name: "call",
+			Function: func(c GoStepsCtx) StepResult {
+				attempts += 1
+				if attempts < 3 {
+					return MarkStateError().WithError(error1)
+				}
+
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 3,
+				RetryAllErrors: true,
+				RateLimiter:    limiter.named("step"),
+			},
+		},
+		{
+			Name: "group",
+			Parallel: &Parallel{
+				Steps: Steps{
+					{Name: "group.step", Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() }},
+				},
+			},
+		},
+	}
+
+	root := NewStepsProcessor(steps)
+	root.BranchOpts.RateLimiter = limiter.named("branch")
+
+	c := NewGoStepsContext().Use(limiter.named("context"))
+	report := root.Execute(c)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+
+	// each attempt waits on the context, branch and step rate limiters, the parallel
+	// group does not wait on the inherited rate limiters, its steps do
+	assert.Equal(t, []string{
+		"context", "branch", "step",
+		"context", "branch", "step",
+		"context", "branch", "step",
+		"context", "branch",
+	}, limiter.waits)
+}
+
+func Test_RateLimiter_WaitDuration(t *testing.T) {
+
+	limiter := &delayLimiter{delay: 20 * time.Millisecond}
+
+	steps := Steps{
+		{
+			Name: "call",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				RateLimiter: limiter,
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.GreaterOrEqual(t, report.Steps[0].WaitDuration, 20*time.Millisecond)
+	assert.Less(t, report.Steps[0].Duration, 20*time.Millisecond)
+}
+
+func Test_RateLimiter_Cancelled(t *testing.T) {
+
+	limiter := &delayLimiter{delay: time.Hour}
+
+	executed := false
+	steps := Steps{
+		{
+			Name: "call",
+			Function: func(c GoStepsCtx) StepResult {
+				executed = true
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	report := NewStepsProcessor(steps).ExecuteContext(ctx, NewGoStepsContext().Use(limiter))
+
+	assert.False(t, executed)
+	assert.Equal(t, StepStateCancelled, report.Outcome)
+	assert.True(t, errors.Is(report.Error, context.DeadlineExceeded))
+	assert.GreaterOrEqual(t, report.Steps[0].WaitDuration, 20*time.Millisecond)
+}
+
+func Test_RateLimiter_Release(t *testing.T) {
+
+	bucket := NewTokenBucket(1, 1).WithClock(&recordingClock{})
+
+	steps := Steps{
+		{
+			Name: "call",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				RateLimiter: &delayLimiter{delay: time.Hour},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	report := NewStepsProcessor(steps).ExecuteContext(ctx, NewGoStepsContext().Use(bucket))
+	assert.Equal(t, StepStateCancelled, report.Outcome)
+
+	// the token taken from the bucket is given back, the attempt waiting on the step rate limiter did not run
+	wait, _ := bucket.reserve()
+	assert.Equal(t, time.Duration(0), wait)
+}
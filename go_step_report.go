@@ -0,0 +1,202 @@
+package gosteps
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BranchSelection type defines the branch selected by
+// the resolver of a step during the step-chain execution
+type BranchSelection struct {
+	StepName   StepName   `json:"stepName"`
+	BranchName BranchName `json:"branchName"`
+}
+
+// UnresolvedBranch type defines a branch name returned by the resolver of a step, that is not in its
+// branches or whose When condition is false, and the OnUnresolved policy applied to the branches
+type UnresolvedBranch struct {
+	StepName   StepName               `json:"stepName"`
+	BranchName BranchName             `json:"branchName"`
+	Policy     UnresolvedBranchPolicy `json:"policy"`
+}
+
+// ExecutionReport type defines the report of a step-chain execution
+type ExecutionReport struct {
+	RunID           RunID              `json:"runId,omitempty"`           // id of the run, if checkpointed
+	Outcome         StepState          `json:"outcome"`                   // final state of the step-chain
+	TerminatingStep *StepProgress      `json:"terminatingStep,omitempty"` // step that stopped the step-chain, if any
+	Steps           []StepProgress     `json:"steps"`                     // progress of each step, in order of execution
+	BranchPath      []BranchSelection  `json:"branchPath"`                // branches taken through the resolvers
+	Unresolved      []UnresolvedBranch `json:"unresolved,omitempty"`      // branch names of the resolvers that were not resolved
+	StartedAt       time.Time          `json:"startedAt"`                 // time at which the execution started
+	Duration        time.Duration      `json:"duration"`                  // total duration of the execution
+	Error           error              `json:"error,omitempty"`           // aggregated error of the execution, if any
+
+	Compensations     []StepProgress `json:"compensations,omitempty"`     // progress of each compensation, in order of execution
+	CompensationError error          `json:"compensationError,omitempty"` // aggregated error of the compensations, if any
+
+	StepName     StepName           `json:"stepName,omitempty"`     // step running the sub-workflow, set on the reports of sub-workflows
+	SubWorkflows []*ExecutionReport `json:"subWorkflows,omitempty"` // reports of the sub-workflows run by the steps, one per attempt
+}
+
+// goStepsRun type tracks the progress of a single step-chain execution
+type goStepsRun struct {
+	mutex           sync.Mutex
+	startedAt       time.Time
+	steps           []StepProgress
+	branchPath      []BranchSelection
+	unresolved      []UnresolvedBranch
+	terminatingStep *StepProgress
+	runID           RunID
+	checkpoint      *checkpointRun
+	compensations   []*Step
+	compensated     []StepProgress
+	subWorkflows    []*ExecutionReport
+}
+
+// newGoStepsRun returns a new run, started now
+func newGoStepsRun() *goStepsRun {
+	return &goStepsRun{
+		startedAt:  time.Now(),
+		steps:      []StepProgress{},
+		branchPath: []BranchSelection{},
+	}
+}
+
+// addStep records the progress of an executed step
+func (run *goStepsRun) addStep(progress StepProgress) {
+	if run == nil {
+		return
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.steps = append(run.steps, progress)
+}
+
+// addBranch records the branch selected by the resolver of a step
+func (run *goStepsRun) addBranch(stepName StepName, branchName BranchName) {
+	if run == nil {
+		return
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.branchPath = append(run.branchPath, BranchSelection{
+		StepName:   stepName,
+		BranchName: branchName,
+	})
+}
+
+// addUnresolved records the branch name of the resolver of a step that was not resolved, and
+// logs a warning if the branches are skipped, as the step-chain carries on without them
+func (ctx GoStepsCtx) addUnresolved(unresolved *UnresolvedBranch) {
+	if unresolved == nil || ctx.run == nil {
+		return
+	}
+
+	record := *unresolved
+	record.StepName = ctx.namespaced(unresolved.StepName)
+
+	ctx.run.mutex.Lock()
+	ctx.run.unresolved = append(ctx.run.unresolved, record)
+	ctx.run.mutex.Unlock()
+
+	if unresolved.Policy == OnUnresolvedSkip {
+		ctx.Log(fmt.Sprintf(
+			"branch [%s] of step [%s] is not resolved, the branches are skipped", record.BranchName, record.StepName,
+		), WarnLevel)
+	}
+}
+
+// terminate records the step that stopped the step-chain execution
+func (run *goStepsRun) terminate(progress StepProgress) {
+	if run == nil {
+		return
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.terminatingStep = &progress
+}
+
+// merge records the steps and branches of another run, example of a parallel step
+func (run *goStepsRun) merge(other *goStepsRun) {
+	if run == nil || other == nil {
+		return
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.steps = append(run.steps, other.steps...)
+	run.branchPath = append(run.branchPath, other.branchPath...)
+	run.unresolved = append(run.unresolved, other.unresolved...)
+	run.compensations = append(run.compensations, other.compensations...)
+	run.subWorkflows = append(run.subWorkflows, other.subWorkflows...)
+}
+
+// report builds the execution report of the run
+func (run *goStepsRun) report() *ExecutionReport {
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	report := &ExecutionReport{
+		RunID:           run.runID,
+		Outcome:         StepStateComplete,
+		TerminatingStep: run.terminatingStep,
+		Steps:           run.steps,
+		BranchPath:      run.branchPath,
+		Unresolved:      run.unresolved,
+		SubWorkflows:    run.subWorkflows,
+		StartedAt:       run.startedAt,
+		Duration:        time.Since(run.startedAt),
+	}
+
+	var errs ExecutionErrors
+	for _, progress := range run.steps {
+		if err := progress.err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if run.terminatingStep != nil {
+		report.Outcome = run.terminatingStep.StepResult.StepState
+	}
+
+	report.Error = errs.errorOrNil()
+
+	var compensationErrs ExecutionErrors
+	for _, progress := range run.compensated {
+		report.Compensations = append(report.Compensations, progress)
+
+		if !progress.StepResult.isDone() {
+			compensationErrs = append(compensationErrs, &CompensationError{
+				StepName:  progress.StepName,
+				StepState: progress.StepResult.StepState,
+				Err:       progress.StepResult.StepError,
+			})
+		}
+	}
+
+	report.CompensationError = compensationErrs.errorOrNil()
+
+	return report
+}
+
+// err returns the error of the step, if the step did not complete
+func (progress StepProgress) err() error {
+	if progress.StepResult.isDone() {
+		return nil
+	}
+
+	return &StepExecutionError{
+		StepName:  progress.StepName,
+		StepState: progress.StepResult.StepState,
+		Err:       progress.StepResult.StepError,
+	}
+}
@@ -0,0 +1,102 @@
+package gosteps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExecutionReport(t *testing.T) {
+
+	branchingStep := Step{
+		Name: "branching",
+		Function: func(c GoStepsCtx) StepResult {
+			return MarkStateComplete()
+		},
+		Branches: &Branches{
+			Resolver: func(ctx GoStepsCtx) BranchName {
+				return "branch1"
+			},
+			Branches: []Branch{
+				{
+					BranchName: "branch1",
+					Steps: Steps{
+						{
+							Name: "branch1.step1",
+							Function: func(c GoStepsCtx) StepResult {
+								return MarkStateError().WithError(error1)
+							},
+							StepOpts: StepOpts{
+								MaxRunAttempts: 3,
+								RetryAllErrors: true,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	steps := Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+		branchingStep,
+		{
+			Name: "step3",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Len(t, report.Steps, 3)
+	assert.Equal(t, []BranchSelection{{StepName: "branching", BranchName: "branch1"}}, report.BranchPath)
+
+	assert.NotNil(t, report.TerminatingStep)
+	assert.Equal(t, StepName("branch1.step1"), report.TerminatingStep.StepName)
+	assert.Equal(t, 3, report.TerminatingStep.RunCount)
+	assert.Equal(t, 3, report.TerminatingStep.MaxRunAttempts)
+
+	var stepErr *StepExecutionError
+	assert.True(t, errors.As(report.Error, &stepErr))
+	assert.Equal(t, StepName("branch1.step1"), stepErr.StepName)
+	assert.True(t, errors.Is(report.Error, error1))
+
+	// re-executing the same step-chain should not accumulate run counts
+	report = NewStepsProcessor(steps).Execute(NewGoStepsContext())
+	assert.Equal(t, 3, report.TerminatingStep.RunCount)
+}
+
+func Test_ExecutionReport_Complete(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+		{
+			Name: "step2",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateSkipped()
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Nil(t, report.TerminatingStep)
+	assert.Nil(t, report.Error)
+	assert.Len(t, report.Steps, 2)
+	assert.Equal(t, StepStateSkipped, report.Steps[1].StepResult.StepState)
+}
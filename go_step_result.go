@@ -1,23 +1,46 @@
 package gosteps
 
+import (
+	"time"
+)
+
 // StepState type defines the state
 // of the step after execution
 type StepState string
 
 const (
-	StepStateComplete StepState = "StepStateComplete" // step completed successfully             [non-retriable]
-	StepStateFailed   StepState = "StepStateFailed"   // step failed to complete, without error  [non-retriable]
-	StepStateSkipped  StepState = "StepStateSkipped"  // step was skipped                        [non-retriable]
-	StepStatePending  StepState = "StepStatePending"  // step is pending, should be retried      [retriable]
-	StepStateError    StepState = "StepStateError"    // step failed to complete, with error     [retriable]
+	StepStateComplete  StepState = "StepStateComplete"  // step completed successfully             [non-retriable]
+	StepStateFailed    StepState = "StepStateFailed"    // step failed to complete, without error  [non-retriable]
+	StepStateSkipped   StepState = "StepStateSkipped"   // step was skipped                        [non-retriable]
+	StepStatePending   StepState = "StepStatePending"   // step is pending, should be retried      [retriable]
+	StepStateError     StepState = "StepStateError"     // step failed to complete, with error     [retriable]
+	StepStateCancelled StepState = "StepStateCancelled" // step was cancelled by the context       [non-retriable]
 )
 
 // StepResult type defines the result of the step
 type StepResult struct {
-	StepData    GoStepsCtxData `json:"stepData"`            // stores the data from a step, if any
-	StepState   StepState      `json:"stepState"`           // state of the step
-	StepMessage *string        `json:"stepMessage"`         // message from the step execution, if any
-	StepError   error          `json:"stepError,omitempty"` // error from the step execution, if any
+	StepData    GoStepsCtxData `json:"stepData"`             // stores the data from a step, if any
+	StepState   StepState      `json:"stepState"`            // state of the step
+	StepMessage *string        `json:"stepMessage"`          // message from the step execution, if any
+	StepError   error          `json:"stepError,omitempty"`  // error from the step execution, if any
+	RetryAfter  *time.Duration `json:"retryAfter,omitempty"` // delay before the next attempt, suggested by the step, if any
+	Control     *StepControl   `json:"control,omitempty"`    // control flow of the step-chain after the step, if any
+}
+
+// ControlAction type defines the control flow of the
+// step-chain after a step, other than the next step
+type ControlAction string
+
+const (
+	ControlGoto     ControlAction = "Goto"     // continue the step-chain from the target step
+	ControlBreak    ControlAction = "Break"    // stop the iterations of the enclosing loop
+	ControlContinue ControlAction = "Continue" // skip the next steps of the iteration of the enclosing loop
+)
+
+// StepControl type defines the control flow of the step-chain after a step
+type StepControl struct {
+	Action ControlAction `json:"action"`
+	Target StepName      `json:"target,omitempty"` // step to continue from, for ControlGoto
 }
 
 // markState marks the state of the step
@@ -27,6 +50,17 @@ func markState(state StepState) StepResult {
 	}
 }
 
+// isDone checks if the step is done, and the step-chain
+// execution can move on to the next step
+func (sr StepResult) isDone() bool {
+	switch sr.StepState {
+	case StepStateComplete, StepStateSkipped:
+		return true
+	default: // StepStateError, StepStatePending, StepStateFailed, StepStateCancelled
+		return false
+	}
+}
+
 // MarkStateComplete marks the state of the step as complete
 func MarkStateComplete() StepResult {
 	return markState(StepStateComplete)
@@ -52,9 +86,27 @@ func MarkStateError() StepResult {
 	return markState(StepStateError)
 }
 
-// WithData sets the data for the step
-func (sr StepResult) WithData(data GoStepsCtxData) StepResult {
-	sr.StepData = data
+// MarkStateCancelled marks the state of the step as cancelled
+func MarkStateCancelled() StepResult {
+	return markState(StepStateCancelled)
+}
+
+// WithData sets the data for the step, the data of more than one
+// map, example of typed keys, is merged, the last value of a key wins
+func (sr StepResult) WithData(data ...GoStepsCtxData) StepResult {
+	if len(data) == 1 {
+		sr.StepData = data[0]
+		return sr
+	}
+
+	merged := GoStepsCtxData{}
+	for _, d := range data {
+		for key, value := range d {
+			merged[key] = value
+		}
+	}
+
+	sr.StepData = merged
 	return sr
 }
 
@@ -69,3 +121,42 @@ func (sr StepResult) WithError(stepErr error) StepResult {
 	sr.StepError = stepErr
 	return sr
 }
+
+// WithRetryAfter sets the delay before the next attempt of the step, example the delay
+// from a Retry-After header, it overrides the delay computed by the StepOpts.Backoff
+func (sr StepResult) WithRetryAfter(delay time.Duration) StepResult {
+	sr.RetryAfter = &delay
+	return sr
+}
+
+// GotoStep continues the step-chain from the step with the name, instead of the next step, the
+// step is looked up in the steps of the branch or loop of the step, then in the enclosing ones.
+// The control flow is applied only if the step completes or is skipped, and the branches of
+// the step are not resolved
+func (sr StepResult) GotoStep(name StepName) StepResult {
+	sr.Control = &StepControl{
+		Action: ControlGoto,
+		Target: name,
+	}
+
+	return sr
+}
+
+// Break stops the iterations of the enclosing loop after the step, the loop completes, see GotoStep
+func (sr StepResult) Break() StepResult {
+	sr.Control = &StepControl{
+		Action: ControlBreak,
+	}
+
+	return sr
+}
+
+// Continue skips the next steps of the iteration of the enclosing loop, the loop
+// continues with its next iteration, see GotoStep
+func (sr StepResult) Continue() StepResult {
+	sr.Control = &StepControl{
+		Action: ControlContinue,
+	}
+
+	return sr
+}
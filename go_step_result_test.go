@@ -33,6 +33,10 @@ func Test_markStatus(t *testing.T) {
 			StepState: StepStateError,
 			Function:  MarkStateError,
 		},
+		{
+			StepState: StepStateCancelled,
+			Function:  MarkStateCancelled,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,198 @@
+package gosteps
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ctxStore type defines the data and progress of the steps of a context, safe for concurrent use.
+// Copies of a GoStepsCtx, example the context passed by value to the steps and branches of a
+// step-chain, share the same store, forked contexts of parallel steps have their own store
+type ctxStore struct {
+	mutex         sync.RWMutex
+	data          GoStepsCtxData
+	stepsProgress map[StepName]StepProgress
+}
+
+// newCtxStore returns a new store with the data provided, and no progress
+func newCtxStore(data GoStepsCtxData) *ctxStore {
+	if data == nil {
+		data = GoStepsCtxData{}
+	}
+
+	return &ctxStore{
+		data:          data,
+		stepsProgress: map[StepName]StepProgress{},
+	}
+}
+
+// fork returns a copy of the store, with a copy of the data and progress
+func (store *ctxStore) fork() *ctxStore {
+	forked := newCtxStore(store.snapshot())
+
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for step, progress := range store.stepsProgress {
+		forked.stepsProgress[step] = progress
+	}
+
+	return forked
+}
+
+// diff returns the data of the store set since the base data was
+// taken, with the values set, and the keys of the data deleted
+func (store *ctxStore) diff(base GoStepsCtxData) (GoStepsCtxData, []string) {
+	data := store.snapshot()
+
+	changed := GoStepsCtxData{}
+	for key, value := range data {
+		if baseValue, ok := base[key]; !ok || !equalData(baseValue, value) {
+			changed[key] = value
+		}
+	}
+
+	deleted := []string{}
+	for key := range base {
+		if _, ok := data[key]; !ok {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+
+	return changed, deleted
+}
+
+// apply sets the data changed, and deletes the data of the keys deleted, of a diff
+func (store *ctxStore) apply(changed GoStepsCtxData, deleted []string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for key, value := range changed {
+		store.data[key] = value
+	}
+
+	for _, key := range deleted {
+		delete(store.data, key)
+	}
+}
+
+// get returns the data of the key
+func (store *ctxStore) get(key string) interface{} {
+	if store == nil {
+		return nil
+	}
+
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.data[key]
+}
+
+// set sets the data of the keys
+func (store *ctxStore) set(data map[string]interface{}) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for key, value := range data {
+		store.data[key] = value
+	}
+}
+
+// snapshot returns a copy of the data
+func (store *ctxStore) snapshot() GoStepsCtxData {
+	data := GoStepsCtxData{}
+	if store == nil {
+		return data
+	}
+
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	for key, value := range store.data {
+		data[key] = value
+	}
+
+	return data
+}
+
+// compareAndSet sets the data of the key to the new value, if the data is the old value
+func (store *ctxStore) compareAndSet(key string, old, new interface{}) bool {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if !equalData(store.data[key], old) {
+		return false
+	}
+
+	store.data[key] = new
+	return true
+}
+
+// update sets the data of the key to the value returned by the function, called with the current data
+func (store *ctxStore) update(key string, fn func(value interface{}) interface{}) interface{} {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	value := fn(store.data[key])
+	store.data[key] = value
+
+	return value
+}
+
+// delete deletes the data of the key
+func (store *ctxStore) delete(key string) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	delete(store.data, key)
+}
+
+// getProgress returns the progress of the step
+func (store *ctxStore) getProgress(step StepName) StepProgress {
+	if store == nil {
+		return StepProgress{}
+	}
+
+	store.mutex.RLock()
+	defer store.mutex.RUnlock()
+
+	return store.stepsProgress[step]
+}
+
+// setProgress sets the progress of the steps
+func (store *ctxStore) setProgress(progress ...StepProgress) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, p := range progress {
+		store.stepsProgress[p.StepName] = p
+	}
+}
+
+// equalData compares data values with ==, or with reflect.DeepEqual if the values are not
+// comparable, example maps and slices, or structs and arrays with interface fields holding them
+func equalData(a, b interface{}) (equal bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	typeA, typeB := reflect.TypeOf(a), reflect.TypeOf(b)
+	if typeA != typeB {
+		return false
+	}
+
+	if !typeA.Comparable() {
+		return reflect.DeepEqual(a, b)
+	}
+
+	// the type is comparable, but == panics if its interface fields hold values that are not
+	defer func() {
+		if recover() != nil {
+			equal = reflect.DeepEqual(a, b)
+		}
+	}()
+
+	return a == b
+}
@@ -0,0 +1,150 @@
+package gosteps
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConcurrentData(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "count",
+			Function: func(c GoStepsCtx) StepResult {
+				var wg sync.WaitGroup
+				for i := 0; i < 50; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+
+						c.UpdateData("count", func(value interface{}) interface{} {
+							count, _ := value.(int)
+							return count + 1
+						})
+						c.SetData("last", i)
+						c.GetData("count")
+						c.Snapshot()
+					}(i)
+				}
+				wg.Wait()
+
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	ctx := NewGoStepsContext()
+	report := NewStepsProcessor(steps).Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 50, ctx.GetData("count"))
+}
+
+func Test_CompareAndSetData(t *testing.T) {
+
+	ctx := NewGoStepsContext()
+
+	testCases := []struct {
+		Old      interface{}
+		New      interface{}
+		Expected bool
+	}{
+		{Old: "missing", New: 1, Expected: false},
+		{Old: nil, New: 1, Expected: true},
+		{Old: nil, New: 2, Expected: false},
+		{Old: 1, New: []int{1}, Expected: true},
+		{Old: []int{1}, New: map[string]int{"a": 1}, Expected: true},
+		{Old: map[string]int{"a": 2}, New: 3, Expected: false},
+		{Old: int64(3), New: 4, Expected: false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.Expected, ctx.CompareAndSetData("key", tc.Old, tc.New), tc)
+	}
+
+	assert.Equal(t, map[string]int{"a": 1}, ctx.GetData("key"))
+}
+
+// holder is a comparable type, with an interface field that can hold values that are not comparable
+type holder struct {
+	Value interface{}
+}
+
+func Test_EqualData_InterfaceFields(t *testing.T) {
+
+	assert.True(t, equalData(holder{Value: []int{1}}, holder{Value: []int{1}}))
+	assert.False(t, equalData(holder{Value: []int{1}}, holder{Value: []int{2}}))
+	assert.True(t, equalData([1]interface{}{map[string]int{"a": 1}}, [1]interface{}{map[string]int{"a": 1}}))
+	assert.True(t, equalData(holder{Value: 1}, holder{Value: 1}))
+
+	// the data of the steps of a parallel group is merged without panicking
+	ctx := NewGoStepsContext()
+	ctx.SetData("holder", holder{Value: []int{1}})
+
+	steps := Steps{
+		{
+			Name: "group",
+			Parallel: &Parallel{
+				Steps: Steps{
+					{
+						Name: "set",
+						Function: func(c GoStepsCtx) StepResult {
+							c.SetData("holder", holder{Value: []int{2}})
+							return MarkStateComplete()
+						},
+					},
+				},
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, holder{Value: []int{2}}, ctx.GetData("holder"))
+}
+
+func Test_Snapshot(t *testing.T) {
+
+	ctx := NewGoStepsContext()
+	ctx.SetData("key", "value")
+
+	snapshot := ctx.Snapshot()
+	snapshot["key"] = "changed"
+	ctx.SetData("other", 1)
+
+	assert.Equal(t, GoStepsCtxData{"key": "changed"}, snapshot)
+	assert.Equal(t, "value", ctx.GetData("key"))
+
+	ctx.DeleteData("other")
+	assert.Equal(t, GoStepsCtxData{"key": "value"}, ctx.Snapshot())
+
+	// copies of the context share the data, data set in a branch is seen by the parent
+	steps := Steps{
+		{
+			Name: "step1",
+			Branches: &Branches{
+				DefaultBranch: "branch",
+				Branches: []Branch{
+					{
+						BranchName: "branch",
+						Steps: Steps{
+							{
+								Name: "step2",
+								Function: func(c GoStepsCtx) StepResult {
+									c.SetData("fromBranch", true)
+									return MarkStateComplete()
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	NewStepsProcessor(steps).Execute(ctx)
+	assert.Equal(t, true, ctx.GetData("fromBranch"))
+}
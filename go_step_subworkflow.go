@@ -0,0 +1,158 @@
+package gosteps
+
+import (
+	"fmt"
+)
+
+// SubWorkflow type defines a step-chain embedded in a step by reference, the same Branch can be
+// used by the sub-workflows of many steps. The sub-workflow runs with its own data, set from the
+// data of the context with Inputs, and its own progress and retries, the data of the completed
+// sub-workflow is set back in the context with Outputs. The steps of the sub-workflow are reported
+// with namespaced names, example the step [fetch] of the branch [auth] run by the step [login] is
+// reported as [login/auth/fetch]
+type SubWorkflow struct {
+	Branch       *Branch           `json:"-"`
+	WorkflowName string            `json:"workflow,omitempty"` // name of the Branch in the Registry, used to load the sub-workflow
+	Inputs       map[string]string `json:"inputs,omitempty"`   // keys of the sub-workflow data, set from the keys of the context data
+	Outputs      map[string]string `json:"outputs,omitempty"`  // keys of the context data, set from the keys of the sub-workflow data
+}
+
+// namespaced returns the name of the step, prefixed with the namespace of the sub-workflow, if any
+func (ctx GoStepsCtx) namespaced(stepName StepName) StepName {
+	return StepName(ctx.namespace) + stepName
+}
+
+// execute runs the steps of a copy of the branch of the sub-workflow, with a forked context that
+// has the inputs as data. A terminated sub-workflow is compensated, and terminates the step with
+// its state, a completed sub-workflow returns its outputs as the data of the step, and its
+// compensations are run with the compensations of the step-chain. The report of the sub-workflow
+// is nested in the report of the step-chain
+func (subWorkflow *SubWorkflow) execute(c GoStepsCtx) StepResult {
+	branch := *subWorkflow.Branch
+
+	// the steps are copied, so that the progress and retries of the steps are isolated from the other
+	// executions of the branch, and the steps of the sub-workflow are namespaced with the branch name
+	branch.Steps = branch.Steps.clone()
+	prefix := fmt.Sprintf("%s/%s/", c.CurrentStep(), branch.BranchName)
+
+	childCtx := c.fork(c.Context())
+	childCtx.store = newCtxStore(mapData(c.Snapshot(), subWorkflow.Inputs))
+	childCtx.namespace = c.namespace + prefix
+	childCtx.path = c.path + prefix
+	childCtx.position = ""
+	childCtx.iteration = 0
+
+	terminated := branch.execute(childCtx)
+	if terminated {
+		childCtx.compensate()
+	}
+
+	report := childCtx.run.report()
+	report.StepName = c.namespaced(c.CurrentStep())
+
+	c.run.addSubWorkflow(report)
+	c.store.setProgress(report.stepsProgress()...)
+
+	if terminated {
+		return markState(report.Outcome).WithError(report.Error)
+	}
+
+	// the compensations of the completed sub-workflow are run with the data of the sub-workflow
+	for _, step := range childCtx.run.compensations {
+		c.run.addCompensation(step.withContext(childCtx, prefix))
+	}
+
+	return MarkStateComplete().WithData(mapData(childCtx.Snapshot(), subWorkflow.Outputs))
+}
+
+// mapData returns the data of the keys of the mapping, set from the data of the mapped keys,
+// keys mapped to missing data are not set
+func mapData(data GoStepsCtxData, mapping map[string]string) GoStepsCtxData {
+	mapped := GoStepsCtxData{}
+	for key, fromKey := range mapping {
+		if value, ok := data[fromKey]; ok {
+			mapped[key] = value
+		}
+	}
+
+	return mapped
+}
+
+// withContext returns a copy of the step with a compensation run with the context of the
+// sub-workflow, and the name prefixed with the namespace of the sub-workflow in the step-chain
+func (step *Step) withContext(c GoStepsCtx, prefix string) *Step {
+	compensate := step.Compensate
+	stepName := step.Name
+
+	return &Step{
+		Name: StepName(prefix) + step.Name,
+		Compensate: func(compensationCtx GoStepsCtx) StepResult {
+			c.ctx = compensationCtx.ctx
+			c.SetCurrentStep(stepName)
+
+			return compensate(c)
+		},
+	}
+}
+
+// addSubWorkflow records the report of a sub-workflow executed by a step
+func (run *goStepsRun) addSubWorkflow(report *ExecutionReport) {
+	if run == nil {
+		return
+	}
+
+	run.mutex.Lock()
+	defer run.mutex.Unlock()
+
+	run.subWorkflows = append(run.subWorkflows, report)
+}
+
+// stepsProgress returns the progress of the steps of the report, and of its sub-workflows
+func (report *ExecutionReport) stepsProgress() []StepProgress {
+	progress := append([]StepProgress{}, report.Steps...)
+	for _, subWorkflow := range report.SubWorkflows {
+		progress = append(progress, subWorkflow.stepsProgress()...)
+	}
+
+	return progress
+}
+
+// clone returns a copy of the steps, and of the steps of their branches, parallel
+// groups and loops, sub-workflows are copied when they are executed
+func (steps Steps) clone() Steps {
+	if steps == nil {
+		return nil
+	}
+
+	cloned := make(Steps, len(steps))
+	for i, step := range steps {
+		step.resetProgress()
+
+		if step.Branches != nil {
+			branches := *step.Branches
+			branches.Branches = make([]Branch, len(step.Branches.Branches))
+			for j, branch := range step.Branches.Branches {
+				branch.Steps = branch.Steps.clone()
+				branches.Branches[j] = branch
+			}
+
+			step.Branches = &branches
+		}
+
+		if step.Parallel != nil {
+			parallel := *step.Parallel
+			parallel.Steps = parallel.Steps.clone()
+			step.Parallel = &parallel
+		}
+
+		if step.Loop != nil {
+			loop := *step.Loop
+			loop.Steps = loop.Steps.clone()
+			step.Loop = &loop
+		}
+
+		cloned[i] = step
+	}
+
+	return cloned
+}
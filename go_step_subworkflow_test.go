@@ -0,0 +1,286 @@
+package gosteps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthWorkflow() *Branch {
+	return &Branch{
+		BranchName: "auth",
+		Steps: Steps{
+			{
+				Name: "authenticate",
+				Function: func(c GoStepsCtx) StepResult {
+					return MarkStateComplete().WithData(map[string]interface{}{
+						"token": "token-" + c.GetData("user").(string),
+					})
+				},
+			},
+			{
+				Name: "validate",
+				Function: func(c GoStepsCtx) StepResult {
+					if c.GetData("secret") != nil {
+						return MarkStateFailed().WithError(error1)
+					}
+
+					return MarkStateComplete()
+				},
+				Branches: &Branches{
+					Resolver: func(c GoStepsCtx) BranchName { return "valid" },
+					Branches: []Branch{{BranchName: "valid"}},
+				},
+			},
+		},
+	}
+}
+
+func Test_SubWorkflow(t *testing.T) {
+
+	auth := newAuthWorkflow()
+
+	steps := Steps{
+		{
+			Name: "login",
+			SubWorkflow: &SubWorkflow{
+				Branch:  auth,
+				Inputs:  map[string]string{"user": "admin"},
+				Outputs: map[string]string{"adminToken": "token"},
+			},
+		},
+		{
+			Name: "impersonate",
+			SubWorkflow: &SubWorkflow{
+				Branch:  auth,
+				Inputs:  map[string]string{"user": "guest"},
+				Outputs: map[string]string{"guestToken": "token"},
+			},
+		},
+	}
+
+	c := NewGoStepsContext()
+	c.WithData(map[string]interface{}{"admin": "alice", "guest": "bob", "secret": "not passed"})
+
+	root := NewStepsProcessor(steps)
+	assert.NoError(t, root.Validate())
+
+	report := root.Execute(c)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, "token-alice", c.GetData("adminToken"))
+	assert.Equal(t, "token-bob", c.GetData("guestToken"))
+	assert.Nil(t, c.GetData("token"))
+
+	// the steps of the sub-workflows are reported in their nested reports only
+	assert.Len(t, report.Steps, 2)
+	assert.Len(t, report.SubWorkflows, 2)
+
+	login := report.SubWorkflows[0]
+	assert.Equal(t, StepName("login"), login.StepName)
+	assert.Equal(t, StepStateComplete, login.Outcome)
+	assert.Equal(t, StepName("login/auth/authenticate"), login.Steps[0].StepName)
+	assert.Equal(t, StepName("login/auth/validate"), login.Steps[1].StepName)
+	assert.Equal(t, []BranchSelection{{StepName: "login/auth/validate", BranchName: "valid"}}, login.BranchPath)
+
+	impersonate := report.SubWorkflows[1]
+	assert.Equal(t, StepName("impersonate"), impersonate.StepName)
+	assert.Equal(t, StepName("impersonate/auth/authenticate"), impersonate.Steps[0].StepName)
+
+	// the progress of the steps of the sub-workflows does not collide
+	ctx := c.getCtx()
+	assert.Equal(t, "token-alice", ctx.GetProgress("login/auth/authenticate").StepResult.StepData["token"])
+	assert.Equal(t, "token-bob", ctx.GetProgress("impersonate/auth/authenticate").StepResult.StepData["token"])
+
+	// the steps of the shared branch are not changed by the executions
+	assert.Nil(t, auth.Steps[0].stepResult)
+}
+
+func Test_SubWorkflow_Retries(t *testing.T) {
+
+	attempts := 0
+	workflow := &Branch{
+		BranchName: "flaky",
+		Steps: Steps{
+			{
+				Name: "call",
+				Function: func(c GoStepsCtx) StepResult {
+					attempts += 1
+					if attempts == 1 {
+						return MarkStateError().WithError(error1)
+					}
+
+					return MarkStateComplete()
+				},
+			},
+		},
+	}
+
+	steps := Steps{
+		{
+			Name:        "remote",
+			SubWorkflow: &SubWorkflow{Branch: workflow},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 2,
+				RetryAllErrors: true,
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 2, report.Steps[0].RunCount)
+
+	// each attempt runs the sub-workflow again, with its own progress
+	assert.Len(t, report.SubWorkflows, 2)
+	assert.Equal(t, StepStateError, report.SubWorkflows[0].Outcome)
+	assert.Equal(t, StepStateComplete, report.SubWorkflows[1].Outcome)
+	assert.Equal(t, 1, report.SubWorkflows[1].Steps[0].RunCount)
+}
+
+func Test_SubWorkflow_Compensation(t *testing.T) {
+
+	compensated := []string{}
+	workflow := &Branch{
+		BranchName: "reserve",
+		Steps: Steps{
+			{
+				Name: "hold",
+				Function: func(c GoStepsCtx) StepResult {
+					return MarkStateComplete().WithData(map[string]interface{}{"hold": "hold-1"})
+				},
+				Compensate: func(c GoStepsCtx) StepResult {
+					compensated = append(compensated, string(c.CurrentStep())+":"+c.GetData("hold").(string))
+					return MarkStateComplete()
+				},
+			},
+			{
+				Name: "confirm",
+				Function: func(c GoStepsCtx) StepResult {
+					if c.GetData("fail") == true {
+						return MarkStateFailed().WithError(error1)
+					}
+
+					return MarkStateComplete()
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name                string
+		failWorkflow        bool
+		expectedCompensated []StepName
+	}{
+		{
+			name:                "terminated sub-workflow is compensated on its own",
+			failWorkflow:        true,
+			expectedCompensated: []StepName{},
+		},
+		{
+			name:                "completed sub-workflow is compensated with the step-chain",
+			failWorkflow:        false,
+			expectedCompensated: []StepName{"reservation/reserve/hold"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			compensated = []string{}
+
+			steps := Steps{
+				{
+					Name: "reservation",
+					SubWorkflow: &SubWorkflow{
+						Branch: workflow,
+						Inputs: map[string]string{"fail": "failWorkflow"},
+					},
+				},
+				{
+					Name: "charge",
+					Function: func(c GoStepsCtx) StepResult {
+						return MarkStateFailed().WithError(error1)
+					},
+				},
+			}
+
+			c := NewGoStepsContext()
+			c.SetData("failWorkflow", tc.failWorkflow)
+
+			report := NewStepsProcessor(steps).Execute(c)
+
+			assert.Equal(t, StepStateFailed, report.Outcome)
+			assert.True(t, errors.Is(report.Error, error1))
+			assert.Equal(t, []string{"hold:hold-1"}, compensated)
+
+			compensations := []StepName{}
+			for _, progress := range report.Compensations {
+				compensations = append(compensations, progress.StepName)
+			}
+			assert.Equal(t, tc.expectedCompensated, compensations)
+
+			if tc.failWorkflow {
+				assert.Equal(t, StepName("reservation"), report.TerminatingStep.StepName)
+				assert.Equal(t, StepName("reservation/reserve/hold"), report.SubWorkflows[0].Compensations[0].StepName)
+			}
+		})
+	}
+}
+
+func Test_SubWorkflow_Validate(t *testing.T) {
+
+	auth := newAuthWorkflow()
+
+	root := NewStepsProcessor(Steps{
+		// the steps of the sub-workflow are namespaced, their names do not collide with the step-chain
+		{Name: "authenticate", SubWorkflow: &SubWorkflow{Branch: auth}},
+		{Name: "missing", SubWorkflow: &SubWorkflow{}},
+		{Name: "both", Function: func(c GoStepsCtx) StepResult { return MarkStateComplete() }, SubWorkflow: &SubWorkflow{Branch: auth}},
+	})
+	root.BranchName = "root"
+
+	auth.Steps = append(auth.Steps, Step{Name: "again", SubWorkflow: &SubWorkflow{Branch: root}})
+
+	err := root.Validate()
+
+	var validationErrors ValidationErrors
+	assert.True(t, errors.As(err, &validationErrors))
+	assert.Len(t, validationErrors, 4)
+	assert.Contains(t, err.Error(), "root/authenticate/subWorkflow[auth]/again/subWorkflow: invalid options: sub-workflow [root] runs one of its enclosing branches")
+	assert.Contains(t, err.Error(), "root/missing/subWorkflow: invalid options: sub-workflow has no branch")
+	assert.Contains(t, err.Error(), "root/both: invalid options: step has a sub-workflow and a function, parallel steps or loop, only one of them is run")
+	assert.Contains(t, err.Error(), "root/both/subWorkflow[auth]/again/subWorkflow: invalid options: sub-workflow [root] runs one of its enclosing branches")
+}
+
+func Test_Plan_SubWorkflow(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "login",
+			SubWorkflow: &SubWorkflow{
+				Branch:  newAuthWorkflow(),
+				Inputs:  map[string]string{"user": "admin"},
+				Outputs: map[string]string{"adminToken": "token"},
+			},
+		},
+	}
+
+	plan := NewStepsProcessor(steps).Plan(NewGoStepsContext(), PlanOpts{
+		Data: GoStepsCtxData{"admin": "alice"},
+		Stubs: map[StepName]StepResult{
+			"login/auth/authenticate": MarkStateComplete().WithData(map[string]interface{}{"token": "stub"}),
+		},
+	})
+
+	assert.Equal(t, StepStateComplete, plan.Outcome)
+
+	stepNames := []StepName{}
+	for _, planned := range plan.Steps {
+		stepNames = append(stepNames, planned.StepName)
+	}
+
+	assert.Equal(t, []StepName{"login", "login/auth/authenticate", "login/auth/validate"}, stepNames)
+	assert.Equal(t, []BranchSelection{{StepName: "login/auth/validate", BranchName: "valid"}}, plan.BranchPath)
+}
@@ -1,7 +1,6 @@
 package gosteps
 
 import (
-	"encoding/json"
 	"regexp"
 	"time"
 )
@@ -19,29 +18,57 @@ type StepFn func(ctx GoStepsCtx) StepResult
 // to determine the branch to execute
 type ResolverFn func(ctx GoStepsCtx) BranchName
 
+// ConditionFn defines the Condition Function
+// to determine if steps should be executed
+type ConditionFn func(ctx GoStepsCtx) bool
+
 // Step type defines a step with all configurations for the step
 type Step struct {
 	Name            StepName               `json:"name"`
 	Function        StepFn                 `json:"-"`
+	Compensate      StepFn                 `json:"-"`
+	FunctionName    string                 `json:"function,omitempty"`
 	StepOpts        StepOpts               `json:"stepConfig"`
 	Branches        *Branches              `json:"branches"`
+	Parallel        *Parallel              `json:"parallel,omitempty"`
+	Loop            *Loop                  `json:"loop,omitempty"`
+	SubWorkflow     *SubWorkflow           `json:"subWorkflow,omitempty"`
+	When            ConditionFn            `json:"-"`
+	WhenName        string                 `json:"when,omitempty"`
+	SkipIf          ConditionFn            `json:"-"`
+	SkipIfName      string                 `json:"skipIf,omitempty"`
+	DependsOn       []StepName             `json:"dependsOn,omitempty"`
 	StepArgs        map[string]interface{} `json:"stepArgs"`
 	stepResult      *StepResult            `json:"-"`
 	stepRunProgress StepRunProgress        `json:"-"`
 }
 
 // stepRunProgress type defines the progress of the step
-// it contains the run/execution count of each step
+// it contains the run/execution count and duration of each step
 type StepRunProgress struct {
-	runCount int `json:"-"`
+	runCount     int           `json:"-"`
+	duration     time.Duration `json:"-"`
+	waitDuration time.Duration `json:"-"`
+	lastSleep    time.Duration `json:"-"`
 }
 
 // Branch type defines a unique step-chain, of the step-tree
 // Branches can be used to define different steps to be executed
 // based on a resolver function
 type Branch struct {
-	BranchName BranchName `json:"branchName"`
-	Steps      Steps      `json:"steps"`
+	BranchName  BranchName   `json:"branchName"`
+	Steps       Steps        `json:"steps"`
+	BranchOpts  BranchOpts   `json:"branchConfig"`
+	Middlewares []Middleware `json:"-"`
+	When        ConditionFn  `json:"-"`
+	WhenName    string       `json:"when,omitempty"`
+}
+
+// BranchOpts type defines the configuration for the branch
+type BranchOpts struct {
+	Timeout               time.Duration `json:"timeout"`
+	ValidateBeforeExecute bool          `json:"validateBeforeExecute"`
+	RateLimiter           RateLimiter   `json:"-"`
 }
 
 // Steps type defines a list of steps
@@ -50,22 +77,44 @@ type Steps []Step
 // Branches type defines a list of branches
 // with a resolver function to determine the branch to execute
 type Branches struct {
-	Branches []Branch   `json:"branches"`
-	Resolver ResolverFn `json:"-"`
+	Branches      []Branch               `json:"branches"`
+	Resolver      ResolverFn             `json:"-"`
+	ResolverName  string                 `json:"resolver,omitempty"`
+	DefaultBranch BranchName             `json:"defaultBranch,omitempty"`
+	OnUnresolved  UnresolvedBranchPolicy `json:"onUnresolved,omitempty"`
 }
 
+// UnresolvedBranchPolicy type defines what to do when the
+// resolver returns a branch name that is not in the branches
+type UnresolvedBranchPolicy string
+
+const (
+	OnUnresolvedSkip    UnresolvedBranchPolicy = "Skip"    // skip the branches, and continue the step-chain
+	OnUnresolvedFail    UnresolvedBranchPolicy = "Fail"    // fail the step with an *UnresolvedBranchError
+	OnUnresolvedDefault UnresolvedBranchPolicy = "Default" // execute the DefaultBranch
+)
+
 // StepOpts type defines the configuration for the step
 type StepOpts struct {
-	ErrorsToRetry        []error         `json:"errorsToRetry"`
-	ErrorPatternsToRetry []regexp.Regexp `json:"errorPatternsToRetry"`
-	RetryAllErrors       bool            `json:"retryAllErrors"`
-	MaxRunAttempts       int             `json:"maxAttempts"`
-	RetrySleep           time.Duration   `json:"retrySleep"`
+	ErrorsToRetry        []error          `json:"errorsToRetry"`
+	ErrorPatternsToRetry []regexp.Regexp  `json:"errorPatternsToRetry"`
+	RetryOnErrorTypes    []interface{}    `json:"-"`
+	RetryIf              func(error) bool `json:"-"`
+	NeverRetryErrors     []error          `json:"neverRetryErrors"`
+	RetryAllErrors       bool             `json:"retryAllErrors"`
+	MaxRunAttempts       int              `json:"maxAttempts"`
+	RetrySleep           time.Duration    `json:"retrySleep"`
+	Backoff              Backoff          `json:"-"`
+	MaxRetrySleep        time.Duration    `json:"maxRetrySleep"`
+	Timeout              time.Duration    `json:"timeout"`
+	TotalTimeout         time.Duration    `json:"totalTimeout"`
+	RateLimiter          RateLimiter      `json:"-"`
 }
 
-// ToJson converts the step-tree to JSON-string
+// ToJson converts the step-tree to JSON-string, the definition written
+// by Branch.Marshal, that can be loaded back using LoadBranch
 func (branch *Branch) ToJson() (string, error) {
-	stepsBytes, err := json.Marshal(branch)
+	stepsBytes, err := branch.Marshal(FormatJSON)
 	if err != nil {
 		return "", err
 	}
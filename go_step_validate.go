@@ -0,0 +1,293 @@
+package gosteps
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrMissingStepName is the error of a step without a name
+	ErrMissingStepName = errors.New("step has no name")
+
+	// ErrMissingFunction is the error of a step without a function, branches, parallel group, loop or sub-workflow
+	ErrMissingFunction = errors.New("step has no function, branches, parallel steps, loop or sub-workflow")
+
+	// ErrMissingResolver is the error of branches without a resolver, default branch or guarded branch
+	ErrMissingResolver = errors.New("branches have no resolver, default branch or guarded branch")
+
+	// ErrDuplicateBranch is the error of branches with more than one branch with the same name
+	ErrDuplicateBranch = errors.New("duplicate branch")
+
+	// ErrUnknownBranch is the error of branches with a default branch that is not in the branches
+	ErrUnknownBranch = errors.New("unknown branch")
+
+	// ErrInvalidOpts is the error of a step or branch with invalid options
+	ErrInvalidOpts = errors.New("invalid options")
+)
+
+// ValidationError type defines a problem of the step-tree, at the path of
+// the step or branch in the step-tree, example root/step2/branches[divide]/step3
+type ValidationError struct {
+	Path string
+	Err  error
+}
+
+// Error returns the error message of the validation error, prefixed with the path
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error of the validation error
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors type defines all the problems of a step-tree
+type ValidationErrors []error
+
+// Error returns the error messages of all the problems, one per line
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Is reports whether any of the problems matches the target
+func (errs ValidationErrors) Is(target error) bool {
+	return ExecutionErrors(errs).Is(target)
+}
+
+// As finds the first problem that matches the target, and if so, sets target to that error
+func (errs ValidationErrors) As(target interface{}) bool {
+	return ExecutionErrors(errs).As(target)
+}
+
+// validator type walks the step-tree and collects the problems
+type validator struct {
+	errs      ValidationErrors
+	stepPaths map[StepName]string
+	workflows map[*Branch]bool // branches being validated, the root branch and the enclosing sub-workflows
+}
+
+// Validate walks the whole step-tree and returns ValidationErrors with all the problems
+// found, each a *ValidationError with the path of the problem, or nil if the tree is valid
+//   - steps without a name, or with a name used by another step
+//   - steps without a function, branches, parallel steps, loop or sub-workflow
+//   - branches without a resolver or default branch, with duplicate names, or an unknown default branch
+//   - negative attempts, durations, or invalid policies and error types in the options
+//   - sub-workflows without a branch, or running one of the enclosing branches
+//   - steps with DependsOn, the dependencies of steps are used only by a Graph
+func (branch *Branch) Validate() error {
+	v := &validator{
+		stepPaths: map[StepName]string{},
+		workflows: map[*Branch]bool{branch: true},
+	}
+
+	v.validateBranch(branch, string(branch.BranchName))
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+
+	return v.errs
+}
+
+// addError adds a problem at the path
+func (v *validator) addError(path string, err error) {
+	v.errs = append(v.errs, &ValidationError{
+		Path: path,
+		Err:  err,
+	})
+}
+
+// validateBranch validates the options and the steps of the branch
+func (v *validator) validateBranch(branch *Branch, path string) {
+	if branch.BranchOpts.Timeout < 0 {
+		v.addError(path, fmt.Errorf("%w: negative Timeout", ErrInvalidOpts))
+	}
+
+	v.validateSteps(branch.Steps, path)
+}
+
+// validateSteps validates each of the steps
+func (v *validator) validateSteps(steps Steps, path string) {
+	for i := range steps {
+		step := &steps[i]
+
+		stepPath := fmt.Sprintf("%s/%s", path, step.Name)
+		if step.Name == "" {
+			stepPath = fmt.Sprintf("%s/steps[%d]", path, i)
+		}
+
+		v.validateStep(step, stepPath)
+	}
+}
+
+// validateStep validates the name, function, options, branches, parallel steps and loop of the step
+func (v *validator) validateStep(step *Step, path string) {
+	if step.Name == "" {
+		v.addError(path, ErrMissingStepName)
+	} else if otherPath, ok := v.stepPaths[step.Name]; ok {
+		v.addError(path, fmt.Errorf("%w: step [%s] is also defined at %s", ErrDuplicateStep, step.Name, otherPath))
+	} else {
+		v.stepPaths[step.Name] = path
+	}
+
+	if step.Function == nil && step.Branches == nil && step.Parallel == nil && step.Loop == nil && step.SubWorkflow == nil {
+		v.addError(path, ErrMissingFunction)
+	}
+
+	if step.Function != nil && step.Parallel != nil {
+		v.addError(path, fmt.Errorf("%w: step has both a function and parallel steps, the function is not run", ErrInvalidOpts))
+	}
+
+	if (step.Function != nil || step.Parallel != nil) && step.Loop != nil {
+		v.addError(path, fmt.Errorf("%w: step has a loop and a function or parallel steps, only one of them is run", ErrInvalidOpts))
+	}
+
+	if (step.Function != nil || step.Parallel != nil || step.Loop != nil) && step.SubWorkflow != nil {
+		v.addError(path, fmt.Errorf("%w: step has a sub-workflow and a function, parallel steps or loop, only one of them is run", ErrInvalidOpts))
+	}
+
+	if len(step.DependsOn) > 0 {
+		v.addError(path, fmt.Errorf("%w: step has DependsOn, which is used only by the steps of a Graph", ErrInvalidOpts))
+	}
+
+	v.validateStepOpts(step.StepOpts, path)
+
+	if step.Branches != nil {
+		v.validateBranches(step.Branches, path)
+	}
+
+	if step.Parallel != nil {
+		v.validateParallel(step.Parallel, path+"/parallel")
+	}
+
+	if step.Loop != nil {
+		v.validateLoop(step.Loop, path+"/loop")
+	}
+
+	if step.SubWorkflow != nil {
+		v.validateSubWorkflow(step.SubWorkflow, path+"/subWorkflow")
+	}
+}
+
+// validateStepOpts validates the attempts, durations and error types of the step options
+func (v *validator) validateStepOpts(stepOpts StepOpts, path string) {
+	if stepOpts.MaxRunAttempts < 0 {
+		v.addError(path, fmt.Errorf("%w: negative MaxRunAttempts", ErrInvalidOpts))
+	}
+
+	durations := []struct {
+		name     string
+		negative bool
+	}{
+		{name: "RetrySleep", negative: stepOpts.RetrySleep < 0},
+		{name: "MaxRetrySleep", negative: stepOpts.MaxRetrySleep < 0},
+		{name: "Timeout", negative: stepOpts.Timeout < 0},
+		{name: "TotalTimeout", negative: stepOpts.TotalTimeout < 0},
+	}
+
+	for _, duration := range durations {
+		if duration.negative {
+			v.addError(path, fmt.Errorf("%w: negative %s", ErrInvalidOpts, duration.name))
+		}
+	}
+
+	for i, errorType := range stepOpts.RetryOnErrorTypes {
+		if targetType := reflect.TypeOf(errorType); !isErrorTypeTarget(targetType) {
+			v.addError(path, fmt.Errorf(
+				"%w: RetryOnErrorTypes[%d] of type %v is not a pointer to an error type or interface", ErrInvalidOpts, i, targetType,
+			))
+		}
+	}
+}
+
+// validateBranches validates the resolver or guards, the policy, the default branch and each of the branches
+func (v *validator) validateBranches(branches *Branches, path string) {
+	if branches.Resolver == nil && branches.DefaultBranch == "" && !branches.isGuarded() {
+		v.addError(path, ErrMissingResolver)
+	}
+
+	switch branches.OnUnresolved {
+	case "", OnUnresolvedSkip, OnUnresolvedFail, OnUnresolvedDefault:
+	default:
+		v.addError(path, fmt.Errorf("%w: unknown OnUnresolved policy [%s]", ErrInvalidOpts, branches.OnUnresolved))
+	}
+
+	if branches.DefaultBranch != "" && branches.getExecutableBranch(branches.DefaultBranch) == nil {
+		v.addError(path, fmt.Errorf("%w: default branch [%s] is not in the branches", ErrUnknownBranch, branches.DefaultBranch))
+	}
+
+	if branches.DefaultBranch == "" && branches.OnUnresolved == OnUnresolvedDefault {
+		v.addError(path, fmt.Errorf("%w: OnUnresolved policy is Default, but no default branch is set", ErrInvalidOpts))
+	}
+
+	branchNames := map[BranchName]bool{}
+	for i := range branches.Branches {
+		branch := &branches.Branches[i]
+		branchPath := fmt.Sprintf("%s/branches[%s]", path, branch.BranchName)
+
+		if branchNames[branch.BranchName] {
+			v.addError(branchPath, fmt.Errorf("%w: branch [%s] is defined more than once", ErrDuplicateBranch, branch.BranchName))
+		}
+		branchNames[branch.BranchName] = true
+
+		v.validateBranch(branch, branchPath)
+	}
+}
+
+// validateParallel validates the options and each of the steps of the parallel group
+func (v *validator) validateParallel(parallel *Parallel, path string) {
+	if parallel.MaxConcurrency < 0 {
+		v.addError(path, fmt.Errorf("%w: negative MaxConcurrency", ErrInvalidOpts))
+	}
+
+	switch parallel.ConflictPolicy {
+	case "", ConflictPolicyLastWins, ConflictPolicyFirstWins, ConflictPolicyError:
+	default:
+		v.addError(path, fmt.Errorf("%w: unknown ConflictPolicy [%s]", ErrInvalidOpts, parallel.ConflictPolicy))
+	}
+
+	v.validateSteps(parallel.Steps, path)
+}
+
+// validateLoop validates the options and each of the steps of the loop
+func (v *validator) validateLoop(loop *Loop, path string) {
+	if loop.MaxIterations < 0 {
+		v.addError(path, fmt.Errorf("%w: negative MaxIterations", ErrInvalidOpts))
+	}
+
+	v.validateSteps(loop.Steps, path)
+}
+
+// validateSubWorkflow validates the branch of the sub-workflow, the steps of the sub-workflow are
+// namespaced, their names are checked against the other steps of the sub-workflow only
+func (v *validator) validateSubWorkflow(subWorkflow *SubWorkflow, path string) {
+	branch := subWorkflow.Branch
+	if branch == nil {
+		v.addError(path, fmt.Errorf("%w: sub-workflow has no branch", ErrInvalidOpts))
+		return
+	}
+
+	if v.workflows[branch] {
+		v.addError(path, fmt.Errorf("%w: sub-workflow [%s] runs one of its enclosing branches", ErrInvalidOpts, branch.BranchName))
+		return
+	}
+
+	sub := &validator{
+		stepPaths: map[StepName]string{},
+		workflows: v.workflows,
+	}
+
+	v.workflows[branch] = true
+	sub.validateBranch(branch, fmt.Sprintf("%s[%s]", path, branch.BranchName))
+	delete(v.workflows, branch)
+
+	v.errs = append(v.errs, sub.errs...)
+}
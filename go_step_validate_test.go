@@ -0,0 +1,159 @@
+package gosteps
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+
+	stepFn := func(c GoStepsCtx) StepResult {
+		return MarkStateComplete()
+	}
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name:     "step1",
+			Function: stepFn,
+		},
+		{
+			Name:     "step2",
+			Function: stepFn,
+			Branches: &Branches{
+				OnUnresolved:  OnUnresolvedDefault,
+				DefaultBranch: "multiply",
+				Branches: []Branch{
+					{
+						BranchName: "divide",
+						Steps: Steps{
+							{
+								Name:     "step1",
+								Function: stepFn,
+							},
+							{
+								Name: "step3",
+							},
+						},
+					},
+					{
+						BranchName: "divide",
+					},
+				},
+			},
+		},
+		{
+			Name:     "step4",
+			Function: stepFn,
+			StepOpts: StepOpts{
+				MaxRunAttempts:    -1,
+				RetryOnErrorTypes: []interface{}{temporaryError{}},
+			},
+		},
+		{
+			Function: stepFn,
+			Parallel: &Parallel{
+				ConflictPolicy: "Merge",
+			},
+		},
+	})
+
+	err := root.Validate()
+
+	var validationErrors ValidationErrors
+	assert.True(t, errors.As(err, &validationErrors))
+
+	expectedErrors := []struct {
+		Path string
+		Err  error
+	}{
+		{Path: "root/step2", Err: ErrUnknownBranch},
+		{Path: "root/step2/branches[divide]/step1", Err: ErrDuplicateStep},
+		{Path: "root/step2/branches[divide]/step3", Err: ErrMissingFunction},
+		{Path: "root/step2/branches[divide]", Err: ErrDuplicateBranch},
+		{Path: "root/step4", Err: ErrInvalidOpts},
+		{Path: "root/step4", Err: ErrInvalidOpts},
+		{Path: "root/steps[3]", Err: ErrMissingStepName},
+		{Path: "root/steps[3]", Err: ErrInvalidOpts},
+		{Path: "root/steps[3]/parallel", Err: ErrInvalidOpts},
+	}
+
+	assert.Len(t, validationErrors, len(expectedErrors))
+	for i, expectedError := range expectedErrors {
+		var validationError *ValidationError
+		assert.True(t, errors.As(validationErrors[i], &validationError))
+		assert.Equal(t, expectedError.Path, validationError.Path)
+		assert.True(t, errors.Is(validationError, expectedError.Err))
+	}
+
+	assert.Contains(t, err.Error(), "root/step2/branches[divide]/step1: duplicate step: step [step1] is also defined at root/step1")
+}
+
+func Test_Validate_Valid(t *testing.T) {
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				RetryOnErrorTypes: []interface{}{new(temporaryError), new(interface{ Temporary() bool })},
+			},
+		},
+	})
+
+	assert.NoError(t, root.Validate())
+}
+
+func Test_Validate_DependsOn(t *testing.T) {
+
+	root := NewStepsProcessor(Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+		{
+			Name: "step2",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+			DependsOn: []StepName{"step1"},
+		},
+	})
+
+	err := root.Validate()
+
+	var validationError *ValidationError
+	assert.True(t, errors.As(err, &validationError))
+	assert.Equal(t, "root/step2", validationError.Path)
+	assert.True(t, errors.Is(err, ErrInvalidOpts))
+}
+
+func Test_ValidateBeforeExecute(t *testing.T) {
+
+	ran := false
+	root := NewStepsProcessor(Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				ran = true
+				return MarkStateComplete()
+			},
+		},
+		{
+			Name: "step1",
+		},
+	})
+	root.BranchOpts.ValidateBeforeExecute = true
+
+	report := root.Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateFailed, report.Outcome)
+	assert.True(t, errors.Is(report.Error, ErrDuplicateStep))
+	assert.True(t, errors.Is(report.Error, ErrMissingFunction))
+	assert.False(t, ran)
+}
@@ -1,16 +1,83 @@
 package gosteps
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 	"time"
 )
 
-// Execute a branch with the context provided
-func (branch *Branch) Execute(c GoStepsContext) {
-	if branch.Steps == nil {
-		return
+var (
+	// errorInterfaceType is the reflect.Type of the error interface
+	errorInterfaceType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Execute a branch with the context provided and
+// returns the report of the step-chain execution
+func (branch *Branch) Execute(c GoStepsContext) *ExecutionReport {
+	return branch.ExecuteContext(c.Context(), c)
+}
+
+// ExecuteContext executes a branch with the context provided, the step-chain
+// execution stops between steps and retries when the context.Context is done
+func (branch *Branch) ExecuteContext(ctx context.Context, c GoStepsContext) *ExecutionReport {
+	goStepsCtx := c.getCtx()
+	goStepsCtx.startRun(ctx)
+
+	if goStepsCtx.checkpointer != nil {
+		runID := goStepsCtx.runID
+		if runID == "" {
+			runID = newRunID()
+		}
+
+		goStepsCtx.run.runID = runID
+		goStepsCtx.run.checkpoint = &checkpointRun{
+			checkpointer: *goStepsCtx.checkpointer,
+			checkpoint:   newCheckpoint(runID),
+		}
 	}
 
-	branch.Steps.execute(c.getCtx())
+	return branch.executeRun(goStepsCtx)
+}
+
+// executeRun validates the branch, if enabled, and executes the steps of the branch, if the step-chain
+// fails the completed steps are compensated, once the step-chain completes or is compensated its
+// checkpoint, if any, is deleted, returns the report of the run
+func (branch *Branch) executeRun(goStepsCtx GoStepsCtx) *ExecutionReport {
+	if branch.BranchOpts.ValidateBeforeExecute {
+		if err := branch.Validate(); err != nil {
+			report := goStepsCtx.run.report()
+			report.Outcome = StepStateFailed
+			report.Error = err
+
+			goStepsCtx.onChainEnd(report)
+			return report
+		}
+	}
+
+	// the checkpoint of a compensated step-chain is deleted too, as the step-chain is rolled back
+	if !branch.execute(goStepsCtx) || goStepsCtx.compensate() {
+		goStepsCtx.run.checkpoints().delete(&goStepsCtx)
+	}
+
+	report := goStepsCtx.run.report()
+	goStepsCtx.onChainEnd(report)
+
+	return report
+}
+
+// execute the steps of the branch within the timeout of the branch, if set,
+// returns true if a step terminated the step-chain execution
+func (branch *Branch) execute(c GoStepsCtx) bool {
+	if branch.BranchOpts.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(c.Context(), branch.BranchOpts.Timeout)
+		defer cancel()
+
+		c.ctx = ctx
+	}
+
+	return branch.Steps.execute(c.withMiddlewares(branch.Middlewares).withRateLimiter(branch.BranchOpts.RateLimiter))
 }
 
 // setProgress sets the run progress (runCount) of a step
@@ -18,6 +85,58 @@ func (step *Step) setProgress() {
 	step.stepRunProgress.runCount += 1
 }
 
+// resetProgress resets the run progress and result of a step
+// before the step is executed, so that runs do not accumulate
+func (step *Step) resetProgress() {
+	step.stepRunProgress = StepRunProgress{}
+	step.stepResult = nil
+}
+
+// getProgress returns the progress of the executed step
+func (step *Step) getProgress() StepProgress {
+	progress := StepProgress{
+		StepName:       step.Name,
+		RunCount:       step.stepRunProgress.runCount,
+		MaxRunAttempts: step.StepOpts.MaxRunAttempts,
+		Duration:       step.stepRunProgress.duration,
+		WaitDuration:   step.stepRunProgress.waitDuration,
+	}
+
+	if step.stepResult != nil {
+		progress.StepResult = *step.stepResult
+	}
+
+	return progress
+}
+
+// stepProgress returns the progress of the executed step, as recorded in the run, with
+// the name namespaced by the sub-workflow, and the iteration of the enclosing loop, if any
+func (ctx GoStepsCtx) stepProgress(step *Step) StepProgress {
+	progress := step.getProgress()
+	progress.StepName = ctx.namespaced(progress.StepName)
+	progress.Iteration = ctx.iteration
+
+	return progress
+}
+
+// stepFn returns the function of the step, a step with a parallel group, a
+// loop or a sub-workflow runs the group, the loop or the sub-workflow as its function
+func (step *Step) stepFn() StepFn {
+	if step.Parallel != nil {
+		return step.Parallel.execute
+	}
+
+	if step.Loop != nil {
+		return step.Loop.execute
+	}
+
+	if step.SubWorkflow != nil {
+		return step.SubWorkflow.execute
+	}
+
+	return step.Function
+}
+
 // setResult sets the result of the executed step
 func (step *Step) setResult(stepResult *StepResult) *Step {
 	step.stepResult = stepResult
@@ -35,17 +154,151 @@ func (step *Step) setDefaults() {
 	}
 }
 
-// sleep for the retry sleep duration of the step
-func (step *Step) sleep() {
-	if step.StepOpts.RetrySleep > 0 {
-		time.Sleep(step.StepOpts.RetrySleep)
+// nextSleep returns the retry sleep duration before the next attempt of the step
+func (step *Step) nextSleep() time.Duration {
+	sleep := step.retrySleep()
+	step.stepRunProgress.lastSleep = sleep
+
+	return sleep
+}
+
+// interrupt marks the step as interrupted by the context, with the error of the context
+// the step is marked as timed out if a timeout of the step or branch exceeded, else as cancelled
+func (step *Step) interrupt(c *GoStepsCtx, err error, elapsed time.Duration) {
+	step.overrideResult(c, step.interruptedResult(*c, err, elapsed))
+}
+
+// overrideResult overrides the result of the step, outside of the step function
+func (step *Step) overrideResult(c *GoStepsCtx, stepResult StepResult) {
+	step.setResult(&stepResult)
+	c.setStepProgress(step)
+
+	if c.logger.config.StepLoggingEnabled {
+		c.log(step)
+	}
+}
+
+// interruptedResult returns the result of a step interrupted by the context, a step interrupted
+// by the deadline of the context.Context the step-chain is executed with is cancelled
+func (step *Step) interruptedResult(c GoStepsCtx, err error, elapsed time.Duration) StepResult {
+	if !c.timedOut(err) {
+		return MarkStateCancelled().WithError(err)
+	}
+
+	if step.stepRunProgress.runCount == 0 {
+		return MarkStateError().WithError(ErrStepTimeout).WithMessage(fmt.Sprintf(
+			"step timed out after %s, before its first attempt", elapsed,
+		))
+	}
+
+	return MarkStateError().WithError(ErrStepTimeout).WithMessage(fmt.Sprintf(
+		"step timed out after %s on attempt %d", elapsed, step.stepRunProgress.runCount,
+	))
+}
+
+// run executes the step with the context provided, and
+// retries the step until it should not be retried anymore
+func (step *Step) run(c *GoStepsCtx) {
+	step.resetProgress()
+	startedAt := time.Now()
+
+	// the total timeout of the step spans across all the attempts
+	if step.StepOpts.TotalTimeout > 0 {
+		ctx, cancel := context.WithTimeout(c.Context(), step.StepOpts.TotalTimeout)
+		defer cancel()
+
+		stepCtx := *c
+		stepCtx.ctx = ctx
+		c = &stepCtx
+	}
+
+	// skip the step if the context is already done
+	if err := c.Context().Err(); err != nil {
+		step.interrupt(c, err, time.Since(startedAt))
+		return
+	}
+
+	// each attempt waits on the rate limiters of the step first
+	if err := step.waitRateLimit(c); err != nil {
+		step.interrupt(c, err, time.Since(startedAt))
+		return
+	}
+
+	step.execute(c)
+	for c.Context().Err() == nil && step.shouldRetry() {
+		sleep := step.nextSleep()
+		c.onRetry(c.stepProgress(step), sleep)
+
+		if err := c.getClock().Sleep(c.Context(), sleep); err != nil {
+			step.interrupt(c, err, time.Since(startedAt))
+			return
+		}
+
+		if err := step.waitRateLimit(c); err != nil {
+			step.interrupt(c, err, time.Since(startedAt))
+			return
+		}
+
+		step.execute(c)
+	}
+}
+
+// call calls the step function within the timeout of the attempt, if set, and returns
+// the error of the context if the context was done before the step was done. If the
+// context has a deadline, the step function is abandoned when the deadline exceeds
+func (step *Step) call(c GoStepsCtx) (StepResult, error) {
+	if step.StepOpts.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(c.Context(), step.StepOpts.Timeout)
+		defer cancel()
+
+		c.ctx = ctx
+	}
+
+	ctx := c.Context()
+	if _, ok := ctx.Deadline(); !ok {
+		stepResult := step.stepFn()(c)
+		if err := ctx.Err(); err != nil && !stepResult.isDone() {
+			return stepResult, err
+		}
+
+		return stepResult, nil
+	}
+
+	// the step function runs with a fork of the data, the progress and the control flow, merged
+	// into the context if it returns in time, an abandoned step function does not change them
+	attemptCtx := c
+	attemptCtx.store = c.store.fork()
+	attemptCtx.run = newGoStepsRun()
+	attemptCtx.flow = &controlFlow{}
+	base := c.store.snapshot()
+
+	stepResultChan := make(chan StepResult, 1)
+	go func() {
+		stepResultChan <- step.stepFn()(attemptCtx)
+	}()
+
+	select {
+	case stepResult := <-stepResultChan:
+		c.store.apply(attemptCtx.store.diff(base))
+		c.merge(attemptCtx.run)
+		if control := attemptCtx.flow.pending(); control != nil {
+			c.flow.set(control)
+		}
+
+		if err := ctx.Err(); err != nil && !stepResult.isDone() {
+			return stepResult, err
+		}
+
+		return stepResult, nil
+	case <-ctx.Done():
+		return StepResult{}, ctx.Err()
 	}
 }
 
 // Execute a step with the context provided
 func (step *Step) execute(c *GoStepsCtx) {
 	// skip if the step function is nil
-	if step.Function == nil {
+	if step.stepFn() == nil {
 		return
 	}
 
@@ -58,8 +311,15 @@ func (step *Step) execute(c *GoStepsCtx) {
 	// set the default values for the step options
 	step.setDefaults()
 
-	// execute the step function
-	stepResult := step.Function(*c)
+	// set the progress of the step in the step
+	step.setProgress()
+
+	// execute the step function, wrapped by the middlewares
+	c.step = step
+	c.attempt = step.stepRunProgress.runCount
+	startedAt := time.Now()
+	stepResult := c.wrap(step.attempt)(*c)
+	step.stepRunProgress.duration += time.Since(startedAt)
 
 	// set the result of the executed step
 	step.setResult(&stepResult)
@@ -68,56 +328,193 @@ func (step *Step) execute(c *GoStepsCtx) {
 	c.WithData(stepResult.StepData)
 
 	// set the progress of the executed step in the context
-	c.SetProgress(step.Name, stepResult)
+	c.setStepProgress(step)
+}
 
-	// set the progress of the executed step in the step
-	step.setProgress()
+// attempt calls the step function, and returns its result, or the result of
+// the interrupted step, if the context was done during the execution
+func (step *Step) attempt(c GoStepsCtx) StepResult {
+	startedAt := time.Now()
 
-	// log the step, if logger is provided
-	if c.logger.config.StepLoggingEnabled {
-		c.log(step)
+	stepResult, err := step.call(c)
+	if err != nil {
+		return step.interruptedResult(c, err, time.Since(startedAt))
 	}
+
+	return stepResult
 }
 
-// Execute a chain of steps with the context provided
-func (steps *Steps) execute(c GoStepsCtx) {
+// Execute a chain of steps with the context provided, returns
+// true if a step terminated the step-chain execution
+func (steps *Steps) execute(c GoStepsCtx) bool {
 	s := *steps
-	if len(s) == 0 {
-		return
-	}
+	c.scope = &stepScope{steps: s, parent: c.scope}
+
+	for i := 0; i < len(s); i++ {
+		currentStep := &s[i]
+		position := c.stepPosition(i)
+
+		// a step completed in the checkpoint of the run is not run again
+		progress, restored := c.run.checkpoints().completedStep(position)
+		ran, skipped := false, false
+		if restored {
+			currentStep.restore(&c, progress)
+		} else if reason, skip := currentStep.skipReason(c); skip {
+			// a skipped step is not run, and its branches are not resolved
+			skipped = true
+			currentStep.resetProgress()
+			currentStep.overrideResult(&c, MarkStateSkipped().WithMessage(reason))
+		} else if err := c.budget.take(); err != nil {
+			currentStep.resetProgress()
+			currentStep.overrideResult(&c, MarkStateFailed().WithError(err))
+		} else {
+			ran = currentStep.stepFn() != nil
+			if ran {
+				c.onStepStart(c.namespaced(currentStep.Name))
+			}
+
+			currentStep.run(&c)
+		}
 
-	currentStepCounter := 0
+		currentStep.setControl(&c)
+
+		var branch *Branch
+		if currentStep.Branches != nil && !skipped && !currentStep.shouldExit() && c.flow.pending() == nil {
+			var err error
+			if branchName, ok := c.run.checkpoints().selectedBranch(position); ok {
+				branch = currentStep.Branches.getExecutableBranch(branchName)
+			} else {
+				var unresolved *UnresolvedBranch
+				branch, unresolved, err = currentStep.Branches.resolve(c, currentStep.Name)
+				c.addUnresolved(unresolved)
+			}
 
-	var currentStep *Step = &s[currentStepCounter]
-	for currentStep != nil {
-		if currentStepCounter >= len(s) {
-			break
+			if err != nil {
+				currentStep.overrideResult(&c, MarkStateError().WithError(err))
+			}
 		}
 
-		currentStep = &s[currentStepCounter]
-		currentStep.execute(&c)
+		if currentStep.stepResult != nil {
+			progress := c.stepProgress(currentStep)
+			c.run.addStep(progress)
 
-		if currentStep.shouldRetry() {
-			currentStep.sleep()
-			continue
+			if ran {
+				c.onStepEnd(progress)
+			}
+
+			if currentStep.Compensate != nil && progress.StepResult.StepState == StepStateComplete {
+				c.run.addCompensation(currentStep)
+			}
 		}
 
+		// the branch selected is recorded once the step is ended, with its final result
+		if branch != nil {
+			c.run.addBranch(c.namespaced(currentStep.Name), branch.BranchName)
+			c.onBranchResolved(BranchSelection{
+				StepName:   c.namespaced(currentStep.Name),
+				BranchName: branch.BranchName,
+			})
+		}
+
+		c.run.checkpoints().save(&c, position, currentStep, branch)
+
 		if currentStep.shouldExit() {
-			break
+			c.run.terminate(c.stepProgress(currentStep))
+			return true
 		}
 
-		branches := currentStep.Branches
-		if branches != nil {
-			branchName := branches.Resolver(c)
-			branch := branches.getExecutableBranch(branchName)
+		if branch != nil {
+			branchCtx := c
+			branchCtx.position = position + "/" + string(branch.BranchName)
+			branchCtx.path = c.path + string(branch.BranchName) + "/"
 
-			if branch != nil {
-				branch.Execute(&c)
+			// a step terminating the branch, terminates the step-chain
+			if branch.execute(branchCtx) {
+				return true
 			}
 		}
 
-		currentStepCounter += 1
+		// a control flow not applying to these steps, is applied by the enclosing steps or loop
+		control := c.flow.pending()
+		if control == nil {
+			continue
+		}
+
+		target := s.indexOf(control.Target)
+		if control.Action != ControlGoto || target < 0 {
+			return false
+		}
+
+		// the steps from the target are run again, their progress in the checkpoint is reset
+		c.flow.set(nil)
+		for j := target; j <= i; j++ {
+			c.run.checkpoints().reset(c.stepPosition(j))
+		}
+
+		i = target - 1
 	}
+
+	return false
+}
+
+// resolve returns the branch to execute based on the resolver result, or without a resolver, the
+// first branch with a When condition that is true. If the resolver returns an unknown branch name,
+// or a branch whose When condition is false, the branch is resolved based on the OnUnresolved policy,
+// and the unresolved branch is returned with the policy applied
+func (branches *Branches) resolve(c GoStepsCtx, stepName StepName) (*Branch, *UnresolvedBranch, error) {
+	var branchName BranchName
+	if branches.Resolver != nil {
+		branchName = branches.Resolver(c)
+	} else {
+		branchName = branches.guardedBranch(c)
+	}
+
+	if branch := branches.getExecutableBranch(branchName); branch != nil && branch.allows(c) {
+		return branch, nil, nil
+	}
+
+	unresolved := &UnresolvedBranch{
+		StepName:   stepName,
+		BranchName: branchName,
+		Policy:     branches.onUnresolved(),
+	}
+
+	unresolvedBranchError := &UnresolvedBranchError{
+		StepName:   stepName,
+		BranchName: branchName,
+	}
+
+	switch unresolved.Policy {
+	case OnUnresolvedDefault:
+		if branch := branches.getExecutableBranch(branches.DefaultBranch); branch != nil && branch.allows(c) {
+			return branch, unresolved, nil
+		}
+
+		return nil, unresolved, unresolvedBranchError
+	case OnUnresolvedFail:
+		return nil, unresolved, unresolvedBranchError
+	default: // OnUnresolvedSkip
+		return nil, unresolved, nil
+	}
+}
+
+// onUnresolved returns the OnUnresolved policy of the branches, if not set, the default branch
+// is used if set, else the step fails if the branch name was returned by a resolver, else, with
+// guarded branches only, the branches are skipped
+func (branches *Branches) onUnresolved() UnresolvedBranchPolicy {
+	if branches.OnUnresolved != "" {
+		return branches.OnUnresolved
+	}
+
+	if branches.DefaultBranch != "" {
+		return OnUnresolvedDefault
+	}
+
+	if branches.Resolver != nil {
+		return OnUnresolvedFail
+	}
+
+	return OnUnresolvedSkip
 }
 
 // getExecutableBranch returns the branch to execute based on the resolver result
@@ -135,14 +532,16 @@ func (branches *Branches) getExecutableBranch(branchName BranchName) *Branch {
 // retry steps, if:
 //   - step state is pending
 //   - step state is error and RetryAllErrors is true
-//   - step state is error and error is in ErrorsToRetry
+//   - step state is error and error matches ErrorsToRetry, ErrorPatternsToRetry,
+//     RetryOnErrorTypes or RetryIf
 //   - step run count is less than MaxRunAttempts
 //
 // skip retry if:
 //   - step state is failed, complete or skipped
+//   - step state is error and error matches NeverRetryErrors
 //   - step run count is equal to MaxRunAttempts
 func (step *Step) shouldRetry() bool {
-	if step.StepOpts.MaxRunAttempts == step.stepRunProgress.runCount {
+	if step.stepRunProgress.runCount >= step.StepOpts.MaxRunAttempts {
 		return false
 	}
 
@@ -158,27 +557,86 @@ func (step *Step) shouldRetry() bool {
 		return true
 	}
 
-	if step.stepResult.StepState == StepStateError && step.StepOpts.RetryAllErrors {
+	if step.stepResult.StepState != StepStateError {
+		return false
+	}
+
+	stepError := step.stepResult.StepError
+	if stepError != nil && matchesAnyError(stepError, step.StepOpts.NeverRetryErrors) {
+		return false
+	}
+
+	if step.StepOpts.RetryAllErrors {
 		return true
 	}
 
-	if step.stepResult.StepState == StepStateError && step.stepResult.StepError != nil {
-		for _, errorToRetry := range step.StepOpts.ErrorsToRetry {
-			if errorToRetry == step.stepResult.StepError {
-				return true
-			}
+	if stepError != nil {
+		return step.isRetryableError(stepError)
+	}
+
+	return false
+}
+
+// isRetryableError checks if the error matches any of the
+// ErrorsToRetry, ErrorPatternsToRetry, RetryOnErrorTypes or RetryIf
+func (step *Step) isRetryableError(err error) bool {
+	if matchesAnyError(err, step.StepOpts.ErrorsToRetry) {
+		return true
+	}
+
+	for _, re := range step.StepOpts.ErrorPatternsToRetry {
+		if re.MatchString(err.Error()) {
+			return true
 		}
+	}
 
-		for _, re := range step.StepOpts.ErrorPatternsToRetry {
-			if re.MatchString(step.stepResult.StepError.Error()) {
-				return true
-			}
+	for _, errorType := range step.StepOpts.RetryOnErrorTypes {
+		if matchesErrorType(err, errorType) {
+			return true
 		}
 	}
 
+	if step.StepOpts.RetryIf != nil && step.StepOpts.RetryIf(err) {
+		return true
+	}
+
 	return false
 }
 
+// matchesAnyError checks if the error matches any of the errors, using errors.Is
+func matchesAnyError(err error, errs []error) bool {
+	for _, target := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesErrorType checks if the error matches the type of the errorType, using errors.As
+// the errorType must be a pointer to the error type or interface, example new(*net.OpError)
+func matchesErrorType(err error, errorType interface{}) bool {
+	targetType := reflect.TypeOf(errorType)
+	if !isErrorTypeTarget(targetType) {
+		return false
+	}
+
+	// a new target is used, so that the errorType is not mutated by errors.As
+	return errors.As(err, reflect.New(targetType.Elem()).Interface())
+}
+
+// isErrorTypeTarget checks if the type is a valid errors.As target type,
+// a pointer to a type that implements error, or to an interface
+func isErrorTypeTarget(targetType reflect.Type) bool {
+	if targetType == nil || targetType.Kind() != reflect.Ptr {
+		return false
+	}
+
+	elemType := targetType.Elem()
+	return elemType.Kind() == reflect.Interface || elemType.Implements(errorInterfaceType)
+}
+
 // shouldExit checks if the step should exists
 // and step-chain execution should be stopped
 func (step *Step) shouldExit() bool {
@@ -186,10 +644,5 @@ func (step *Step) shouldExit() bool {
 		return false
 	}
 
-	switch step.stepResult.StepState {
-	case StepStateComplete, StepStateSkipped:
-		return false
-	default: // StepStateError, StepStatePending, StepStateFailed
-		return true
-	}
+	return !step.stepResult.isDone()
 }
@@ -0,0 +1,123 @@
+package gosteps
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrStepTimeout is the error of a step that did not complete within
+	// its timeout, it can be used in StepOpts.ErrorsToRetry to retry timeouts
+	ErrStepTimeout = errors.New("step timed out")
+
+	// ErrUnresolvedBranch is the error of a step, whose resolver returned a branch name
+	// that is not in its branches, the error is of type *UnresolvedBranchError
+	ErrUnresolvedBranch = errors.New("unresolved branch")
+)
+
+// UnresolvedBranchError type defines the error of a step, whose
+// resolver returned a branch name that is not in its branches
+type UnresolvedBranchError struct {
+	StepName   StepName
+	BranchName BranchName
+}
+
+// Error returns the error message of the unresolved branch error
+func (e *UnresolvedBranchError) Error() string {
+	return fmt.Sprintf("error: branch [%s] of step [%s] is unresolved, no branch found with this name", e.BranchName, e.StepName)
+}
+
+// Is reports whether the target is ErrUnresolvedBranch
+func (e *UnresolvedBranchError) Is(target error) bool {
+	return target == ErrUnresolvedBranch
+}
+
+// StepExecutionError type defines the error of a step
+// that terminated the step-chain execution
+type StepExecutionError struct {
+	StepName  StepName
+	StepState StepState
+	Err       error
+}
+
+// Error returns the error message of the step execution error
+func (e *StepExecutionError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("step [%s] terminated with state %s", e.StepName, e.StepState)
+	}
+
+	return fmt.Sprintf("step [%s] terminated with state %s: %v", e.StepName, e.StepState, e.Err)
+}
+
+// Unwrap returns the underlying error of the step, if any
+func (e *StepExecutionError) Unwrap() error {
+	return e.Err
+}
+
+// CompensationError type defines the error of a compensation
+// of a step, that did not complete
+type CompensationError struct {
+	StepName  StepName
+	StepState StepState
+	Err       error
+}
+
+// Error returns the error message of the compensation error
+func (e *CompensationError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("compensation of step [%s] ended with state %s", e.StepName, e.StepState)
+	}
+
+	return fmt.Sprintf("compensation of step [%s] ended with state %s: %v", e.StepName, e.StepState, e.Err)
+}
+
+// Unwrap returns the underlying error of the compensation, if any
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
+
+// ExecutionErrors type defines a list of errors
+// aggregated over the execution of a step-chain
+type ExecutionErrors []error
+
+// Error returns the error messages of all the errors, separated by ';'
+func (errs ExecutionErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether any of the errors matches the target
+func (errs ExecutionErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first error that matches the target, and if so, sets target to that error
+func (errs ExecutionErrors) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// errorOrNil returns the errors as an error, or nil if there are no errors
+func (errs ExecutionErrors) errorOrNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
@@ -1,9 +1,13 @@
 package gosteps
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -130,6 +134,103 @@ func Test_shouldRetry(t *testing.T) {
 	}
 }
 
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "temporary" }
+func (temporaryError) Temporary() bool { return true }
+
+func Test_shouldRetry_errorMatching(t *testing.T) {
+
+	wrappedError1 := fmt.Errorf("wrapped: %w", error1)
+	wrappedTemporaryError := fmt.Errorf("wrapped: %w", temporaryError{})
+
+	testCases := []struct {
+		StepOpts            StepOpts
+		StepError           error
+		ExpectedShouldRetry bool
+	}{
+		{
+			StepOpts: StepOpts{
+				ErrorsToRetry: []error{error1},
+			},
+			StepError:           wrappedError1,
+			ExpectedShouldRetry: true,
+		},
+		{
+			StepOpts: StepOpts{
+				ErrorsToRetry: []error{errors.New("error1")},
+			},
+			StepError:           wrappedError1,
+			ExpectedShouldRetry: false,
+		},
+		{
+			StepOpts: StepOpts{
+				RetryIf: func(err error) bool {
+					return errors.Is(err, error1)
+				},
+			},
+			StepError:           wrappedError1,
+			ExpectedShouldRetry: true,
+		},
+		{
+			StepOpts: StepOpts{
+				RetryOnErrorTypes: []interface{}{new(temporaryError)},
+			},
+			StepError:           wrappedTemporaryError,
+			ExpectedShouldRetry: true,
+		},
+		{
+			StepOpts: StepOpts{
+				RetryOnErrorTypes: []interface{}{new(interface{ Temporary() bool })},
+			},
+			StepError:           wrappedTemporaryError,
+			ExpectedShouldRetry: true,
+		},
+		{
+			StepOpts: StepOpts{
+				RetryOnErrorTypes: []interface{}{new(interface{ Temporary() bool }), temporaryError{}},
+			},
+			StepError:           wrappedError1,
+			ExpectedShouldRetry: false,
+		},
+		{
+			StepOpts: StepOpts{
+				RetryAllErrors:   true,
+				NeverRetryErrors: []error{error1},
+			},
+			StepError:           wrappedError1,
+			ExpectedShouldRetry: false,
+		},
+		{
+			StepOpts: StepOpts{
+				RetryAllErrors:   true,
+				NeverRetryErrors: []error{error1},
+			},
+			StepError:           wrappedTemporaryError,
+			ExpectedShouldRetry: true,
+		},
+	}
+
+	for _, tc := range testCases {
+
+		tc.StepOpts.MaxRunAttempts = 2
+		step := Step{
+			StepOpts: tc.StepOpts,
+			stepResult: &StepResult{
+				StepState: StepStateError,
+				StepError: tc.StepError,
+			},
+			stepRunProgress: StepRunProgress{
+				runCount: 1,
+			},
+		}
+
+		shouldRetry := step.shouldRetry()
+
+		assert.Equal(t, tc.ExpectedShouldRetry, shouldRetry)
+	}
+}
+
 func Test_shouldExit(t *testing.T) {
 
 	testCases := []struct {
@@ -205,6 +306,205 @@ func Test_getExecutableBranch(t *testing.T) {
 
 }
 
+func Test_resolve(t *testing.T) {
+
+	resolver := func(branchName BranchName) ResolverFn {
+		return func(ctx GoStepsCtx) BranchName {
+			return branchName
+		}
+	}
+
+	testCases := []struct {
+		Branches           Branches
+		ExpectedBranchName BranchName
+		ExpectedError      bool
+	}{
+		{
+			Branches: Branches{
+				Resolver: resolver("branch1"),
+			},
+			ExpectedBranchName: "branch1",
+		},
+		{
+			Branches: Branches{
+				Resolver: resolver("branch3"),
+			},
+			ExpectedError: true,
+		},
+		{
+			Branches: Branches{
+				Resolver:     resolver("branch3"),
+				OnUnresolved: OnUnresolvedSkip,
+			},
+		},
+		{
+			Branches: Branches{
+				Resolver:      resolver("branch3"),
+				DefaultBranch: "branch2",
+			},
+			ExpectedBranchName: "branch2",
+		},
+		{
+			Branches: Branches{
+				Resolver:      resolver("branch3"),
+				DefaultBranch: "branch2",
+				OnUnresolved:  OnUnresolvedSkip,
+			},
+		},
+		{
+			Branches: Branches{
+				Resolver:     resolver("branch3"),
+				OnUnresolved: OnUnresolvedFail,
+			},
+			ExpectedError: true,
+		},
+		{
+			Branches: Branches{
+				Resolver:      resolver("branch3"),
+				DefaultBranch: "branch4",
+			},
+			ExpectedError: true,
+		},
+		{
+			Branches: Branches{
+				DefaultBranch: "branch2",
+			},
+			ExpectedBranchName: "branch2",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc.Branches.Branches = []Branch{
+			{BranchName: "branch1"},
+			{BranchName: "branch2"},
+		}
+
+		branch, _, err := tc.Branches.resolve(GoStepsCtx{}, "step1")
+		if tc.ExpectedError {
+			var unresolvedBranchError *UnresolvedBranchError
+			assert.True(t, errors.As(err, &unresolvedBranchError))
+			assert.Equal(t, StepName("step1"), unresolvedBranchError.StepName)
+			assert.Nil(t, branch)
+			continue
+		}
+
+		assert.NoError(t, err)
+		if tc.ExpectedBranchName == "" {
+			assert.Nil(t, branch)
+			continue
+		}
+
+		assert.Equal(t, tc.ExpectedBranchName, branch.BranchName)
+	}
+}
+
+func Test_Execute_UnresolvedBranch(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "resolving",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+			Branches: &Branches{
+				Resolver: func(ctx GoStepsCtx) BranchName {
+					return "multiple"
+				},
+				OnUnresolved: OnUnresolvedFail,
+				Branches: []Branch{
+					{BranchName: "multiply"},
+				},
+			},
+		},
+		{
+			Name: "never",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, StepName("resolving"), report.TerminatingStep.StepName)
+	assert.True(t, errors.Is(report.Error, ErrUnresolvedBranch))
+	assert.EqualError(t, report.TerminatingStep.StepResult.StepError, "error: branch [multiple] of step [resolving] is unresolved, no branch found with this name")
+	assert.Len(t, report.Steps, 1)
+}
+
+func Test_Execute_UnresolvedBranch_Report(t *testing.T) {
+
+	testCases := []struct {
+		OnUnresolved    UnresolvedBranchPolicy
+		DefaultBranch   BranchName
+		ExpectedOutcome StepState
+		ExpectedPath    []BranchSelection
+	}{
+		{
+			OnUnresolved:    "",
+			ExpectedOutcome: StepStateError,
+			ExpectedPath:    []BranchSelection{},
+		},
+		{
+			OnUnresolved:    OnUnresolvedSkip,
+			ExpectedOutcome: StepStateComplete,
+			ExpectedPath:    []BranchSelection{},
+		},
+		{
+			DefaultBranch:   "multiply",
+			ExpectedOutcome: StepStateComplete,
+			ExpectedPath:    []BranchSelection{{StepName: "resolving", BranchName: "multiply"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		steps := Steps{
+			{
+				Name: "resolving",
+				Function: func(c GoStepsCtx) StepResult {
+					return MarkStateComplete()
+				},
+				Branches: &Branches{
+					Resolver: func(ctx GoStepsCtx) BranchName {
+						return "multiple"
+					},
+					OnUnresolved:  tc.OnUnresolved,
+					DefaultBranch: tc.DefaultBranch,
+					Branches: []Branch{
+						{BranchName: "multiply"},
+					},
+				},
+			},
+		}
+
+		var logs bytes.Buffer
+		ctx := NewGoStepsContext().Use(NewGoStepsLogger(&logs, nil))
+		report := NewStepsProcessor(steps).Execute(ctx)
+
+		// the unresolved branch name is reported with the policy applied, whatever the policy
+		policy := tc.OnUnresolved
+		switch {
+		case policy != "":
+		case tc.DefaultBranch != "":
+			policy = OnUnresolvedDefault
+		default:
+			policy = OnUnresolvedFail
+		}
+
+		assert.Equal(t, tc.ExpectedOutcome, report.Outcome)
+		assert.Equal(t, tc.ExpectedPath, report.BranchPath)
+		assert.Equal(t, []UnresolvedBranch{{StepName: "resolving", BranchName: "multiple", Policy: policy}}, report.Unresolved)
+
+		if policy == OnUnresolvedSkip {
+			assert.Contains(t, logs.String(), `"level":"warn"`)
+			assert.Contains(t, logs.String(), "branch [multiple] of step [resolving] is not resolved, the branches are skipped")
+		} else {
+			assert.NotContains(t, logs.String(), "is not resolved")
+		}
+	}
+}
+
 func Test_setDefaults(t *testing.T) {
 
 	step := Step{
@@ -262,7 +562,7 @@ func Test_Main(t *testing.T) {
 				if nx%2 == 0 {
 					return BranchName("divide")
 				}
-				return BranchName("multiple")
+				return BranchName("multiply")
 			},
 			Branches: []Branch{
 				{
@@ -352,3 +652,264 @@ func Test_Main(t *testing.T) {
 
 	assert.Equal(t, 1, ctxData.(int))
 }
+
+func Test_ExecuteContext_Cancelled(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	steps := Steps{
+		{
+			Name: "step1",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).ExecuteContext(ctx, NewGoStepsContext())
+
+	assert.Equal(t, StepStateCancelled, report.Outcome)
+	assert.Equal(t, 0, report.TerminatingStep.RunCount)
+	assert.True(t, errors.Is(report.Error, context.Canceled))
+}
+
+func Test_ExecuteContext_CancelRetrySleep(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	steps := Steps{
+		{
+			Name: "pending",
+			Function: func(c GoStepsCtx) StepResult {
+				go cancel()
+				return MarkStatePending()
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 3,
+				RetrySleep:     time.Hour,
+			},
+		},
+		{
+			Name: "step2",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).ExecuteContext(ctx, NewGoStepsContext())
+
+	assert.Equal(t, StepStateCancelled, report.Outcome)
+	assert.Equal(t, StepName("pending"), report.TerminatingStep.StepName)
+	assert.Equal(t, 1, report.TerminatingStep.RunCount)
+	assert.Len(t, report.Steps, 1)
+}
+
+func Test_ExecuteContext_StepListensDone(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	steps := Steps{
+		{
+			Name: "listening",
+			Function: func(c GoStepsCtx) StepResult {
+				cancel()
+				<-c.Done()
+				return MarkStateError().WithError(c.Context().Err())
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 3,
+				RetryAllErrors: true,
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).ExecuteContext(ctx, NewGoStepsContext())
+
+	assert.Equal(t, StepStateCancelled, report.Outcome)
+	assert.Equal(t, 1, report.TerminatingStep.RunCount)
+}
+
+func Test_StepTimeout(t *testing.T) {
+
+	var attempts int32
+	steps := Steps{
+		{
+			Name: "hung",
+			Function: func(c GoStepsCtx) StepResult {
+				if atomic.AddInt32(&attempts, 1) < 3 {
+					<-c.Done()
+				}
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 3,
+				Timeout:        10 * time.Millisecond,
+				ErrorsToRetry:  []error{ErrStepTimeout},
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 3, report.Steps[0].RunCount)
+	assert.GreaterOrEqual(t, report.Steps[0].Duration, 20*time.Millisecond)
+
+	steps = Steps{
+		{
+			Name: "hung",
+			Function: func(c GoStepsCtx) StepResult {
+				<-c.Done()
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 2,
+				Timeout:        10 * time.Millisecond,
+			},
+		},
+	}
+
+	report = NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, ErrStepTimeout, report.TerminatingStep.StepResult.StepError)
+	assert.Equal(t, 1, report.TerminatingStep.RunCount)
+	assert.Contains(t, *report.TerminatingStep.StepResult.StepMessage, "on attempt 1")
+	assert.True(t, errors.Is(report.Error, ErrStepTimeout))
+}
+
+func Test_StepTimeout_AbandonedStep(t *testing.T) {
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	steps := Steps{
+		{
+			Name: "hung",
+			Function: func(c GoStepsCtx) StepResult {
+				defer close(done)
+
+				<-release
+				c.SetData("late", true)
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				Timeout: 10 * time.Millisecond,
+			},
+		},
+	}
+
+	ctx := NewGoStepsContext()
+	report := NewStepsProcessor(steps).Execute(ctx)
+
+	close(release)
+	<-done
+
+	// the data set by the abandoned step function is not merged into the context
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Nil(t, ctx.GetData("late"))
+
+	steps = Steps{
+		{
+			Name: "inTime",
+			Function: func(c GoStepsCtx) StepResult {
+				c.SetData("set", c.Attempt())
+				c.DeleteData("deleted")
+				return MarkStateComplete()
+			},
+			StepOpts: StepOpts{
+				Timeout: time.Second,
+			},
+		},
+	}
+
+	ctx = NewGoStepsContext()
+	ctx.SetData("deleted", true)
+	report = NewStepsProcessor(steps).Execute(ctx)
+
+	assert.Equal(t, StepStateComplete, report.Outcome)
+	assert.Equal(t, 1, ctx.GetData("set"))
+	assert.Equal(t, GoStepsCtxData{"set": 1}, ctx.Snapshot())
+}
+
+func Test_ExecuteContext_Deadline(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "hung",
+			Function: func(c GoStepsCtx) StepResult {
+				<-c.Done()
+				return MarkStateError().WithError(c.Context().Err())
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 3,
+				Timeout:        time.Second,
+				ErrorsToRetry:  []error{ErrStepTimeout},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// the deadline of the caller cancels the step, it is not a timeout of the step
+	report := NewStepsProcessor(steps).ExecuteContext(ctx, NewGoStepsContext())
+
+	assert.Equal(t, StepStateCancelled, report.Outcome)
+	assert.Equal(t, 1, report.TerminatingStep.RunCount)
+	assert.True(t, errors.Is(report.Error, context.DeadlineExceeded))
+	assert.False(t, errors.Is(report.Error, ErrStepTimeout))
+}
+
+func Test_StepTotalTimeout(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "pending",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStatePending()
+			},
+			StepOpts: StepOpts{
+				MaxRunAttempts: 100,
+				RetrySleep:     10 * time.Millisecond,
+				TotalTimeout:   35 * time.Millisecond,
+			},
+		},
+	}
+
+	report := NewStepsProcessor(steps).Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, ErrStepTimeout, report.TerminatingStep.StepResult.StepError)
+	assert.Less(t, report.TerminatingStep.RunCount, 100)
+}
+
+func Test_BranchTimeout(t *testing.T) {
+
+	steps := Steps{
+		{
+			Name: "slow",
+			Function: func(c GoStepsCtx) StepResult {
+				time.Sleep(20 * time.Millisecond)
+				return MarkStateComplete()
+			},
+		},
+		{
+			Name: "never",
+			Function: func(c GoStepsCtx) StepResult {
+				return MarkStateComplete()
+			},
+		},
+	}
+
+	root := NewStepsProcessor(steps)
+	root.BranchOpts.Timeout = 10 * time.Millisecond
+
+	report := root.Execute(NewGoStepsContext())
+
+	assert.Equal(t, StepStateError, report.Outcome)
+	assert.Equal(t, StepName("slow"), report.TerminatingStep.StepName)
+	assert.Equal(t, ErrStepTimeout, report.TerminatingStep.StepResult.StepError)
+}
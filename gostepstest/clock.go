@@ -0,0 +1,66 @@
+package gostepstest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FakeClock type defines a gosteps.Clock that does not sleep, the sleeps between the attempts
+// of the steps return at once, and advance the time of the clock. Register the clock in the
+// context with Use. The clock is safe for concurrent use
+type FakeClock struct {
+	mutex  sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+// NewFakeClock returns a new fake clock, set to the current time
+func NewFakeClock() *FakeClock {
+	return &FakeClock{
+		now:    time.Now(),
+		sleeps: []time.Duration{},
+	}
+}
+
+// Sleep records the sleep and advances the clock by its duration, without sleeping,
+// returns the error of the context if it is done
+func (clock *FakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.sleeps = append(clock.sleeps, d)
+	clock.now = clock.now.Add(d)
+
+	return nil
+}
+
+// Now returns the time of the clock, advanced by the sleeps
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// Sleeps returns the durations of the sleeps, in order
+func (clock *FakeClock) Sleeps() []time.Duration {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return append([]time.Duration{}, clock.sleeps...)
+}
+
+// Slept returns the total duration of the sleeps
+func (clock *FakeClock) Slept() time.Duration {
+	var slept time.Duration
+	for _, sleep := range clock.Sleeps() {
+		slept += sleep
+	}
+
+	return slept
+}
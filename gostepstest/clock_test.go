@@ -0,0 +1,53 @@
+package gostepstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gosteps "github.com/TanmoySG/go-steps"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FakeClock(t *testing.T) {
+
+	clock := NewFakeClock()
+	startedAt := clock.Now()
+
+	steps := gosteps.Steps{
+		{
+			Name: "step1",
+			Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+				return gosteps.MarkStateError().WithError(errTemporary)
+			},
+			StepOpts: gosteps.StepOpts{
+				MaxRunAttempts: 4,
+				RetryAllErrors: true,
+				Backoff:        gosteps.ExponentialBackoff{Initial: time.Minute, Multiplier: 2},
+			},
+		},
+	}
+
+	executedAt := time.Now()
+	report := gosteps.NewStepsProcessor(steps).Execute(gosteps.NewGoStepsContext().Use(clock))
+
+	assert.Less(t, time.Since(executedAt), time.Second)
+	assert.Equal(t, gosteps.StepStateError, report.Outcome)
+	assert.Equal(t, 4, report.Steps[0].RunCount)
+
+	assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute}, clock.Sleeps())
+	assert.Equal(t, 7*time.Minute, clock.Slept())
+	assert.Equal(t, startedAt.Add(7*time.Minute), clock.Now())
+}
+
+func Test_FakeClock_Cancelled(t *testing.T) {
+
+	clock := NewFakeClock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.True(t, errors.Is(clock.Sleep(ctx, time.Minute), context.Canceled))
+	assert.Empty(t, clock.Sleeps())
+}
@@ -0,0 +1,134 @@
+// Package gostepstest provides helpers to test step-chains: a Recorder of the attempts of the
+// steps, with assertions, a Stub of the results of named steps, and a FakeClock making the
+// sleeps between the attempts instant. Register them in the context of the execution
+//
+//	recorder := gostepstest.NewRecorder()
+//	stub := gostepstest.NewStub().Step("fetch", errorResult, errorResult, gosteps.MarkStateComplete())
+//	ctx := gosteps.NewGoStepsContext().Use(recorder.Middleware, stub.Middleware, gostepstest.NewFakeClock())
+package gostepstest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	gosteps "github.com/TanmoySG/go-steps"
+	"github.com/stretchr/testify/assert"
+)
+
+// Attempt type defines an attempt of a step, recorded by the Recorder
+type Attempt struct {
+	StepName gosteps.StepName       // name of the step
+	Path     string                 // path of the step through the branches, example divide/step3.divide
+	Attempt  int                    // attempt of the step, from 1
+	Data     gosteps.GoStepsCtxData // data of the context before the attempt, with the step args
+	Result   gosteps.StepResult     // result of the attempt
+}
+
+// Recorder type defines a recorder of the attempts of the steps, register the Middleware of the
+// recorder in the context with Use, before the other middlewares and stubs, so that it records
+// the results of the stubs. The recorder is safe for concurrent use, the attempts of parallel
+// steps are recorded in order of completion
+type Recorder struct {
+	mutex    sync.Mutex
+	attempts []Attempt
+}
+
+// NewRecorder returns a new recorder, without attempts
+func NewRecorder() *Recorder {
+	return &Recorder{
+		attempts: []Attempt{},
+	}
+}
+
+// Middleware records the data and the result of each attempt of the steps, steps without a
+// function, and steps skipped by their conditions, are not run and are not recorded
+func (recorder *Recorder) Middleware(next gosteps.StepFn) gosteps.StepFn {
+	return func(c gosteps.GoStepsCtx) gosteps.StepResult {
+		attempt := Attempt{
+			StepName: c.CurrentStep(),
+			Path:     c.StepPath(),
+			Attempt:  c.Attempt(),
+			Data:     c.Snapshot(),
+		}
+
+		attempt.Result = next(c)
+
+		recorder.mutex.Lock()
+		recorder.attempts = append(recorder.attempts, attempt)
+		recorder.mutex.Unlock()
+
+		return attempt.Result
+	}
+}
+
+// Attempts returns all the recorded attempts, in order
+func (recorder *Recorder) Attempts() []Attempt {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return append([]Attempt{}, recorder.attempts...)
+}
+
+// AttemptsOf returns the recorded attempts of the step, the step is
+// matched by its path, example divide/step3.divide, or by its name
+func (recorder *Recorder) AttemptsOf(step string) []Attempt {
+	attempts := []Attempt{}
+	for _, attempt := range recorder.Attempts() {
+		if attempt.Path == step || string(attempt.StepName) == step {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return attempts
+}
+
+// Path returns the paths of the steps run, in order, a step retried is listed once per run
+func (recorder *Recorder) Path() []string {
+	path := []string{}
+	for _, attempt := range recorder.Attempts() {
+		if attempt.Attempt <= 1 {
+			path = append(path, attempt.Path)
+		}
+	}
+
+	return path
+}
+
+// Reset removes the recorded attempts, to reuse the recorder
+func (recorder *Recorder) Reset() {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.attempts = []Attempt{}
+}
+
+// AssertStepRan asserts that the step was attempted the number of times, across all its runs,
+// the step is matched by its path, example divide/step3.divide, or by its name
+func (recorder *Recorder) AssertStepRan(t testing.TB, step string, times int) bool {
+	t.Helper()
+
+	attempts := len(recorder.AttemptsOf(step))
+	return assert.Equalf(t, times, attempts, "step [%s] ran %d times, expected %d times", step, attempts, times)
+}
+
+// AssertPath asserts that the steps run are the steps of the path, in order, each step of the
+// path is the path of the step through the branches, example "step1", "divide/step3.divide"
+func (recorder *Recorder) AssertPath(t testing.TB, path ...string) bool {
+	t.Helper()
+
+	actual := recorder.Path()
+	return assert.Equalf(t, path, actual, "steps ran [%s], expected [%s]", strings.Join(actual, ", "), strings.Join(path, ", "))
+}
+
+// AssertResult asserts that the last attempt of the step ended with the state
+func (recorder *Recorder) AssertResult(t testing.TB, step string, state gosteps.StepState) bool {
+	t.Helper()
+
+	attempts := recorder.AttemptsOf(step)
+	if len(attempts) == 0 {
+		return assert.Failf(t, "step did not run", "step [%s] did not run, expected state %s", step, state)
+	}
+
+	return assert.Equalf(t, state, attempts[len(attempts)-1].Result.StepState, "state of step [%s]", step)
+}
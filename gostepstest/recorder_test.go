@@ -0,0 +1,91 @@
+package gostepstest
+
+import (
+	"errors"
+	"testing"
+
+	gosteps "github.com/TanmoySG/go-steps"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTemporary = errors.New("temporary error")
+
+func newDivideChain() *gosteps.Branch {
+	return gosteps.NewStepsProcessor(gosteps.Steps{
+		{
+			Name: "step1",
+			Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+				return gosteps.MarkStateComplete().WithData(map[string]interface{}{"n": 10})
+			},
+			Branches: &gosteps.Branches{
+				Resolver: func(c gosteps.GoStepsCtx) gosteps.BranchName { return "divide" },
+				Branches: []gosteps.Branch{
+					{
+						BranchName: "divide",
+						Steps: gosteps.Steps{
+							{
+								Name:     "step3.divide",
+								StepArgs: map[string]interface{}{"by": 2},
+								Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+									return gosteps.MarkStateComplete().WithData(map[string]interface{}{
+										"n": c.GetData("n").(int) / c.GetData("by").(int),
+									})
+								},
+								StepOpts: gosteps.StepOpts{
+									MaxRunAttempts: 3,
+									ErrorsToRetry:  []error{errTemporary},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: "step2",
+			Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+				return gosteps.MarkStateComplete()
+			},
+		},
+	})
+}
+
+func Test_Recorder(t *testing.T) {
+
+	recorder := NewRecorder()
+	c := gosteps.NewGoStepsContext().Use(recorder.Middleware)
+
+	report := newDivideChain().Execute(c)
+	assert.Equal(t, gosteps.StepStateComplete, report.Outcome)
+
+	recorder.AssertPath(t, "step1", "divide/step3.divide", "step2")
+	recorder.AssertStepRan(t, "step1", 1)
+	recorder.AssertStepRan(t, "divide/step3.divide", 1)
+	recorder.AssertStepRan(t, "step3.divide", 1)
+	recorder.AssertStepRan(t, "unknown", 0)
+	recorder.AssertResult(t, "step2", gosteps.StepStateComplete)
+
+	// the data of the attempt is the data before the step, with the step args
+	attempt := recorder.AttemptsOf("step3.divide")[0]
+	assert.Equal(t, gosteps.StepName("step3.divide"), attempt.StepName)
+	assert.Equal(t, 1, attempt.Attempt)
+	assert.Equal(t, 10, attempt.Data["n"])
+	assert.Equal(t, 2, attempt.Data["by"])
+	assert.Equal(t, 5, attempt.Result.StepData["n"])
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Attempts())
+}
+
+func Test_Recorder_Failures(t *testing.T) {
+
+	recorder := NewRecorder()
+	newDivideChain().Execute(gosteps.NewGoStepsContext().Use(recorder.Middleware))
+
+	mockT := &testing.T{}
+	assert.False(t, recorder.AssertStepRan(mockT, "step1", 2))
+	assert.False(t, recorder.AssertPath(mockT, "step1", "step2"))
+	assert.False(t, recorder.AssertResult(mockT, "unknown", gosteps.StepStateComplete))
+	assert.False(t, recorder.AssertResult(mockT, "step1", gosteps.StepStateFailed))
+	assert.True(t, mockT.Failed())
+}
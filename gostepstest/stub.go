@@ -0,0 +1,81 @@
+package gostepstest
+
+import (
+	"sync"
+
+	gosteps "github.com/TanmoySG/go-steps"
+)
+
+// Stub type defines scripted results of named steps, returned instead of calling the step
+// functions. Register the Middleware of the stub in the context with Use, after the Recorder.
+// The stub is safe for concurrent use
+type Stub struct {
+	mutex   sync.Mutex
+	results map[string][]gosteps.StepResult
+	calls   map[string]int
+}
+
+// NewStub returns a new stub, without scripted results
+func NewStub() *Stub {
+	return &Stub{
+		results: map[string][]gosteps.StepResult{},
+		calls:   map[string]int{},
+	}
+}
+
+// Step scripts the results of the step, the step is matched by its path, example
+// divide/step3.divide, or by its name. Each attempt of the step returns the next result,
+// the last result is returned once all are returned, example error, error and complete
+func (stub *Stub) Step(step string, results ...gosteps.StepResult) *Stub {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+
+	stub.results[step] = results
+	stub.calls[step] = 0
+
+	return stub
+}
+
+// Calls returns the number of results of the step returned by the stub
+func (stub *Stub) Calls(step string) int {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+
+	return stub.calls[step]
+}
+
+// Middleware returns the next scripted result of the stubbed steps, instead of
+// calling the step function, the functions of the other steps are called
+func (stub *Stub) Middleware(next gosteps.StepFn) gosteps.StepFn {
+	return func(c gosteps.GoStepsCtx) gosteps.StepResult {
+		if stepResult, ok := stub.next(c.StepPath(), string(c.CurrentStep())); ok {
+			return stepResult
+		}
+
+		return next(c)
+	}
+}
+
+// next returns the next scripted result of the step, by its path, or by its name
+func (stub *Stub) next(keys ...string) (gosteps.StepResult, bool) {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+
+	for _, key := range keys {
+		results, ok := stub.results[key]
+		if !ok || len(results) == 0 {
+			continue
+		}
+
+		call := stub.calls[key]
+		stub.calls[key] = call + 1
+
+		if call >= len(results) {
+			call = len(results) - 1
+		}
+
+		return results[call], true
+	}
+
+	return gosteps.StepResult{}, false
+}
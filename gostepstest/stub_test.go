@@ -0,0 +1,52 @@
+package gostepstest
+
+import (
+	"errors"
+	"testing"
+
+	gosteps "github.com/TanmoySG/go-steps"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Stub(t *testing.T) {
+
+	recorder := NewRecorder()
+	stub := NewStub().
+		Step("divide/step3.divide",
+			gosteps.MarkStateError().WithError(errTemporary),
+			gosteps.MarkStateError().WithError(errTemporary),
+			gosteps.MarkStateComplete().WithData(map[string]interface{}{"n": 1}),
+		)
+
+	c := gosteps.NewGoStepsContext().Use(recorder.Middleware, stub.Middleware, NewFakeClock())
+	report := newDivideChain().Execute(c)
+
+	assert.Equal(t, gosteps.StepStateComplete, report.Outcome)
+	assert.Equal(t, 1, c.GetData("n"))
+	assert.Equal(t, 3, stub.Calls("divide/step3.divide"))
+
+	recorder.AssertStepRan(t, "divide/step3.divide", 3)
+	recorder.AssertPath(t, "step1", "divide/step3.divide", "step2")
+
+	states := []gosteps.StepState{}
+	for _, attempt := range recorder.AttemptsOf("step3.divide") {
+		states = append(states, attempt.Result.StepState)
+	}
+	assert.Equal(t, []gosteps.StepState{gosteps.StepStateError, gosteps.StepStateError, gosteps.StepStateComplete}, states)
+}
+
+func Test_Stub_LastResultRepeats(t *testing.T) {
+
+	stub := NewStub().Step("step1", gosteps.MarkStateFailed().WithError(errTemporary))
+
+	c := gosteps.NewGoStepsContext().Use(stub.Middleware)
+
+	for i := 0; i < 2; i++ {
+		report := newDivideChain().Execute(c)
+
+		assert.Equal(t, gosteps.StepStateFailed, report.Outcome)
+		assert.True(t, errors.Is(report.Error, errTemporary))
+	}
+
+	assert.Equal(t, 2, stub.Calls("step1"))
+}
@@ -0,0 +1,32 @@
+module github.com/TanmoySG/go-steps/metrics
+
+go 1.18
+
+require (
+	github.com/TanmoySG/go-steps v1.1.0
+	github.com/prometheus/client_golang v1.14.0
+	github.com/stretchr/testify v1.9.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.37.0 // indirect
+	github.com/prometheus/procfs v0.8.0 // indirect
+	github.com/rs/zerolog v1.33.0 // indirect
+	golang.org/x/sys v0.27.0 // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// go-steps v1.1.0 is the first release with the Middleware, Hooks, GoStepsContext.Use and StepName
+// APIs used by the module, it is replaced by the parent directory for development. Release go-steps
+// first, bump the require above to its tag if it is not v1.1.0, and then tag metrics/<version>
+replace github.com/TanmoySG/go-steps => ../
@@ -0,0 +1,166 @@
+// Package metrics exposes Prometheus metrics of go-steps step-chains, the duration of
+// the steps and step-chains, and counters of attempts, states, retries and branches
+package metrics
+
+import (
+	gosteps "github.com/TanmoySG/go-steps"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labels of the metrics
+const (
+	ChainLabel   = "chain"
+	StepLabel    = "step"
+	StateLabel   = "state"
+	OutcomeLabel = "outcome"
+	BranchLabel  = "branch"
+)
+
+// Option type defines an option of the metrics
+type Option func(opts *options)
+
+// options type defines the options of the metrics
+type options struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace sets the namespace of the metrics, gosteps if not set
+func WithNamespace(namespace string) Option {
+	return func(opts *options) {
+		opts.namespace = namespace
+	}
+}
+
+// WithBuckets sets the buckets of the duration histograms, in seconds, prometheus.DefBuckets if not set
+func WithBuckets(buckets []float64) Option {
+	return func(opts *options) {
+		opts.buckets = buckets
+	}
+}
+
+// Metrics type defines the Prometheus metrics of the step-chains
+type Metrics struct {
+	stepDuration     *prometheus.HistogramVec
+	chainDuration    *prometheus.HistogramVec
+	attempts         *prometheus.CounterVec
+	stepStates       *prometheus.CounterVec
+	retriesExhausted *prometheus.CounterVec
+	branchSelections *prometheus.CounterVec
+}
+
+// New creates the metrics and registers them with the registerer, returns
+// an error if the metrics are already registered with the registerer
+func New(registerer prometheus.Registerer, opts ...Option) (*Metrics, error) {
+	o := &options{
+		namespace: "gosteps",
+		buckets:   prometheus.DefBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	metrics := &Metrics{
+		stepDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "step_duration_seconds",
+			Help:      "Duration of the steps, across all their attempts, in seconds.",
+			Buckets:   o.buckets,
+		}, []string{ChainLabel, StepLabel}),
+		chainDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "chain_duration_seconds",
+			Help:      "Duration of the step-chains, in seconds.",
+			Buckets:   o.buckets,
+		}, []string{ChainLabel, OutcomeLabel}),
+		attempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "step_attempts_total",
+			Help:      "Attempts of the steps, by the state of the attempt.",
+		}, []string{ChainLabel, StepLabel, StateLabel}),
+		stepStates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "step_states_total",
+			Help:      "Executed steps, by their final state.",
+		}, []string{ChainLabel, StepLabel, StateLabel}),
+		retriesExhausted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "step_retries_exhausted_total",
+			Help:      "Steps that did not complete within their maximum run attempts.",
+		}, []string{ChainLabel, StepLabel}),
+		branchSelections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "branch_selections_total",
+			Help:      "Branches selected by the resolvers of the steps.",
+		}, []string{ChainLabel, StepLabel, BranchLabel}),
+	}
+
+	collectors := []prometheus.Collector{
+		metrics.stepDuration,
+		metrics.chainDuration,
+		metrics.attempts,
+		metrics.stepStates,
+		metrics.retriesExhausted,
+		metrics.branchSelections,
+	}
+
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return metrics, nil
+}
+
+// Use adds the middleware and hooks of the metrics to the context, the
+// metrics of the step-chains executed with the context are labelled with the chain
+func (m *Metrics) Use(c gosteps.GoStepsContext, chain string) gosteps.GoStepsContext {
+	return c.Use(m.Middleware(chain), m.Hooks(chain))
+}
+
+// Middleware returns the middleware counting the attempts of the steps, by state
+func (m *Metrics) Middleware(chain string) gosteps.Middleware {
+	return func(next gosteps.StepFn) gosteps.StepFn {
+		return func(c gosteps.GoStepsCtx) gosteps.StepResult {
+			stepResult := next(c)
+			m.attempts.WithLabelValues(chain, string(c.StepName()), string(stepResult.StepState)).Inc()
+
+			return stepResult
+		}
+	}
+}
+
+// Hooks returns the hooks observing the steps, branches and step-chains
+func (m *Metrics) Hooks(chain string) gosteps.Hooks {
+	return gosteps.Hooks{
+		OnStepEnd: func(c gosteps.GoStepsCtx, progress gosteps.StepProgress) {
+			step := string(progress.StepName)
+			state := progress.StepResult.StepState
+
+			m.stepDuration.WithLabelValues(chain, step).Observe(progress.Duration.Seconds())
+			m.stepStates.WithLabelValues(chain, step, string(state)).Inc()
+
+			if retriesExhausted(progress) {
+				m.retriesExhausted.WithLabelValues(chain, step).Inc()
+			}
+		},
+		OnBranchResolved: func(c gosteps.GoStepsCtx, selection gosteps.BranchSelection) {
+			m.branchSelections.WithLabelValues(chain, string(selection.StepName), string(selection.BranchName)).Inc()
+		},
+		OnChainEnd: func(c gosteps.GoStepsCtx, report *gosteps.ExecutionReport) {
+			m.chainDuration.WithLabelValues(chain, string(report.Outcome)).Observe(report.Duration.Seconds())
+		},
+	}
+}
+
+// retriesExhausted checks if a step with retries ended with a retriable state, after all its run attempts
+func retriesExhausted(progress gosteps.StepProgress) bool {
+	switch progress.StepResult.StepState {
+	case gosteps.StepStateError, gosteps.StepStatePending:
+		return progress.MaxRunAttempts > 1 && progress.RunCount >= progress.MaxRunAttempts
+	default:
+		return false
+	}
+}
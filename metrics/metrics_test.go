@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	gosteps "github.com/TanmoySG/go-steps"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient error")
+
+func Test_Metrics(t *testing.T) {
+
+	registry := prometheus.NewRegistry()
+	metrics, err := New(registry)
+	assert.NoError(t, err)
+
+	root := gosteps.NewStepsProcessor(gosteps.Steps{
+		{
+			Name: "check",
+			Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+				return gosteps.MarkStateComplete()
+			},
+			Branches: &gosteps.Branches{
+				DefaultBranch: "charge",
+				Branches: []gosteps.Branch{
+					{
+						BranchName: "charge",
+						Steps: gosteps.Steps{
+							{
+								Name: "charge",
+								Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+									return gosteps.MarkStateError().WithError(errTransient)
+								},
+								StepOpts: gosteps.StepOpts{
+									MaxRunAttempts: 3,
+									RetryAllErrors: true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	report := root.Execute(metrics.Use(gosteps.NewGoStepsContext(), "payment"))
+	assert.Equal(t, gosteps.StepStateError, report.Outcome)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.attempts.WithLabelValues("payment", "check", "StepStateComplete")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(metrics.attempts.WithLabelValues("payment", "charge", "StepStateError")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.stepStates.WithLabelValues("payment", "check", "StepStateComplete")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.stepStates.WithLabelValues("payment", "charge", "StepStateError")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.retriesExhausted.WithLabelValues("payment", "charge")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.retriesExhausted.WithLabelValues("payment", "check")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.branchSelections.WithLabelValues("payment", "check", "charge")))
+
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.stepDuration))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.chainDuration, "gosteps_chain_duration_seconds"))
+
+	// the metrics cannot be registered twice with the same registerer
+	_, err = New(registry)
+	assert.Error(t, err)
+
+	_, err = New(registry, WithNamespace("checkout"), WithBuckets([]float64{0.1, 1}))
+	assert.NoError(t, err)
+}
+
+func Test_Metrics_SubWorkflow(t *testing.T) {
+
+	metrics, err := New(prometheus.NewRegistry())
+	assert.NoError(t, err)
+
+	auth := &gosteps.Branch{
+		BranchName: "auth",
+		Steps: gosteps.Steps{
+			{
+				Name: "authenticate",
+				Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+					return gosteps.MarkStateComplete()
+				},
+			},
+		},
+	}
+
+	root := gosteps.NewStepsProcessor(gosteps.Steps{
+		{Name: "login", SubWorkflow: &gosteps.SubWorkflow{Branch: auth}},
+		{Name: "impersonate", SubWorkflow: &gosteps.SubWorkflow{Branch: auth}},
+	})
+
+	report := root.Execute(metrics.Use(gosteps.NewGoStepsContext(), "session"))
+	assert.Equal(t, gosteps.StepStateComplete, report.Outcome)
+
+	// the attempts and states of the steps of the sub-workflows are labelled with the same namespaced names
+	for _, step := range []string{"login/auth/authenticate", "impersonate/auth/authenticate"} {
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.attempts.WithLabelValues("session", step, "StepStateComplete")))
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.stepStates.WithLabelValues("session", step, "StepStateComplete")))
+	}
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.attempts.WithLabelValues("session", "authenticate", "StepStateComplete")))
+}
@@ -0,0 +1,28 @@
+module github.com/TanmoySG/go-steps/otel
+
+go 1.18
+
+require (
+	github.com/TanmoySG/go-steps v1.1.0
+	github.com/stretchr/testify v1.9.0
+	go.opentelemetry.io/otel v1.14.0
+	go.opentelemetry.io/otel/sdk v1.14.0
+	go.opentelemetry.io/otel/trace v1.14.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rs/zerolog v1.33.0 // indirect
+	golang.org/x/sys v0.27.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// go-steps v1.1.0 is the first release with the ExecuteContext, Middleware, Hooks, GoStepsContext.Use
+// and StepName APIs used by the module, it is replaced by the parent directory for development. Release
+// go-steps first, bump the require above to its tag if it is not v1.1.0, and then tag otel/<version>
+replace github.com/TanmoySG/go-steps => ../
@@ -0,0 +1,255 @@
+// Package otel traces the execution of go-steps step-chains with OpenTelemetry, with
+// a span per step-chain, a span per step and a span per attempt of the step
+package otel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gosteps "github.com/TanmoySG/go-steps"
+	otelapi "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer of the package
+const instrumentationName = "github.com/TanmoySG/go-steps/otel"
+
+// attribute keys of the spans
+const (
+	ChainNameKey      = attribute.Key("gosteps.chain.name")
+	ChainOutcomeKey   = attribute.Key("gosteps.chain.outcome")
+	RunIDKey          = attribute.Key("gosteps.run.id")
+	StepNameKey       = attribute.Key("gosteps.step.name")
+	StepStateKey      = attribute.Key("gosteps.step.state")
+	RunCountKey       = attribute.Key("gosteps.step.run_count")
+	MaxRunAttemptsKey = attribute.Key("gosteps.step.max_run_attempts")
+	AttemptKey        = attribute.Key("gosteps.step.attempt")
+	BranchNameKey     = attribute.Key("gosteps.branch.name")
+	RetrySleepKey     = attribute.Key("gosteps.retry.sleep")
+)
+
+// Option type defines an option of the tracer
+type Option func(tracer *Tracer)
+
+// WithTracerProvider sets the tracer provider, the global tracer provider is used if not set
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(tracer *Tracer) {
+		tracer.provider = provider
+	}
+}
+
+// Tracer type defines the tracing of step-chains, the tracer adds a middleware and hooks to
+// the context, spans of steps are children of the span in the context.Context of the step-chain,
+// spans of attempts are children of the span of their step, and are set in the context.Context
+// passed to the step function, so that step functions can create child spans
+type Tracer struct {
+	provider trace.TracerProvider
+	tracer   trace.Tracer
+
+	mutex sync.Mutex
+	steps map[stepKey]*stepSpan
+}
+
+// stepKey type defines the key of the span of a step, steps are identified by their name
+// and the span of their parent, so that concurrent step-chains do not share spans
+type stepKey struct {
+	parent trace.SpanID
+	step   gosteps.StepName
+}
+
+// stepSpan type defines the span of a step in progress
+type stepSpan struct {
+	span     trace.Span
+	ctx      context.Context
+	attempts int
+}
+
+// NewTracer creates a new tracer with the options provided
+func NewTracer(opts ...Option) *Tracer {
+	tracer := &Tracer{
+		steps: map[stepKey]*stepSpan{},
+	}
+
+	for _, opt := range opts {
+		opt(tracer)
+	}
+
+	if tracer.provider == nil {
+		tracer.provider = otelapi.GetTracerProvider()
+	}
+
+	tracer.tracer = tracer.provider.Tracer(instrumentationName)
+
+	return tracer
+}
+
+// Use adds the tracing middleware and hooks to the context, example:
+// tracer.Use(gosteps.NewGoStepsContext())
+func (t *Tracer) Use(c gosteps.GoStepsContext) gosteps.GoStepsContext {
+	return c.Use(t.Middleware(), t.Hooks())
+}
+
+// Execute executes the branch within a span of the step-chain, the span is tagged
+// with the outcome, the run id and the error of the execution report
+func (t *Tracer) Execute(ctx context.Context, branch *gosteps.Branch, c gosteps.GoStepsContext) *gosteps.ExecutionReport {
+	ctx, span := t.tracer.Start(ctx, "chain "+string(branch.BranchName), trace.WithAttributes(
+		ChainNameKey.String(string(branch.BranchName)),
+	))
+	defer span.End()
+
+	report := branch.ExecuteContext(ctx, c)
+
+	span.SetAttributes(ChainOutcomeKey.String(string(report.Outcome)))
+	if report.RunID != "" {
+		span.SetAttributes(RunIDKey.String(string(report.RunID)))
+	}
+
+	if report.Outcome != gosteps.StepStateComplete {
+		setError(span, report.Error, string(report.Outcome))
+	}
+
+	return report
+}
+
+// Middleware returns the middleware creating a span per attempt of the steps
+func (t *Tracer) Middleware() gosteps.Middleware {
+	return func(next gosteps.StepFn) gosteps.StepFn {
+		return func(c gosteps.GoStepsCtx) gosteps.StepResult {
+			step := c.StepName()
+			parent := c.Context()
+			attempt := 1
+
+			t.mutex.Lock()
+			if s, ok := t.steps[newStepKey(c.Context(), step)]; ok {
+				s.attempts += 1
+				attempt = s.attempts
+				parent = s.ctx
+			}
+			t.mutex.Unlock()
+
+			_, span := t.tracer.Start(parent, "attempt "+string(step), trace.WithAttributes(
+				StepNameKey.String(string(step)),
+				AttemptKey.Int(attempt),
+			))
+			defer span.End()
+
+			// the span of the attempt replaces the parent span, the deadline of the context is kept
+			stepResult := next(c.WithContext(trace.ContextWithSpan(c.Context(), span)))
+
+			span.SetAttributes(StepStateKey.String(string(stepResult.StepState)))
+			if !isDone(stepResult.StepState) {
+				setError(span, stepResult.StepError, string(stepResult.StepState))
+			}
+
+			return stepResult
+		}
+	}
+}
+
+// Hooks returns the hooks creating a span per step
+func (t *Tracer) Hooks() gosteps.Hooks {
+	return gosteps.Hooks{
+		OnStepStart:      t.onStepStart,
+		OnStepEnd:        t.onStepEnd,
+		OnRetry:          t.onRetry,
+		OnBranchResolved: t.onBranchResolved,
+	}
+}
+
+// onStepStart starts the span of the step, as a child of the span in the context
+func (t *Tracer) onStepStart(c gosteps.GoStepsCtx, step gosteps.StepName) {
+	ctx, span := t.tracer.Start(c.Context(), "step "+string(step), trace.WithAttributes(
+		StepNameKey.String(string(step)),
+	))
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.steps[newStepKey(c.Context(), step)] = &stepSpan{
+		span: span,
+		ctx:  ctx,
+	}
+}
+
+// onStepEnd ends the span of the step, tagged with the final progress of the step
+func (t *Tracer) onStepEnd(c gosteps.GoStepsCtx, progress gosteps.StepProgress) {
+	key := newStepKey(c.Context(), progress.StepName)
+
+	t.mutex.Lock()
+	s, ok := t.steps[key]
+	delete(t.steps, key)
+	t.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	s.span.SetAttributes(
+		StepStateKey.String(string(progress.StepResult.StepState)),
+		RunCountKey.Int(progress.RunCount),
+		MaxRunAttemptsKey.Int(progress.MaxRunAttempts),
+	)
+
+	if !isDone(progress.StepResult.StepState) {
+		setError(s.span, progress.StepResult.StepError, string(progress.StepResult.StepState))
+	}
+
+	s.span.End()
+}
+
+// onRetry adds a retry event to the span of the step, with the retry sleep duration
+func (t *Tracer) onRetry(c gosteps.GoStepsCtx, progress gosteps.StepProgress, sleep time.Duration) {
+	if span := t.stepSpan(c, progress.StepName); span != nil {
+		span.AddEvent("retry", trace.WithAttributes(
+			RunCountKey.Int(progress.RunCount),
+			RetrySleepKey.String(sleep.String()),
+		))
+	}
+}
+
+// onBranchResolved adds the branch selected as an event of the parent span of the step,
+// the span of the step is ended before the branch is resolved, see gosteps.Hooks
+func (t *Tracer) onBranchResolved(c gosteps.GoStepsCtx, selection gosteps.BranchSelection) {
+	trace.SpanFromContext(c.Context()).AddEvent("branch resolved", trace.WithAttributes(
+		StepNameKey.String(string(selection.StepName)),
+		BranchNameKey.String(string(selection.BranchName)),
+	))
+}
+
+// stepSpan returns the span of the step in progress, if any
+func (t *Tracer) stepSpan(c gosteps.GoStepsCtx, step gosteps.StepName) trace.Span {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if s, ok := t.steps[newStepKey(c.Context(), step)]; ok {
+		return s.span
+	}
+
+	return nil
+}
+
+// newStepKey returns the key of the span of the step, with the span in the context as parent
+func newStepKey(ctx context.Context, step gosteps.StepName) stepKey {
+	return stepKey{
+		parent: trace.SpanFromContext(ctx).SpanContext().SpanID(),
+		step:   step,
+	}
+}
+
+// isDone checks if the state of the step lets the step-chain move on to the next step
+func isDone(state gosteps.StepState) bool {
+	return state == gosteps.StepStateComplete || state == gosteps.StepStateSkipped
+}
+
+// setError records the error on the span, and sets the status of the span as error
+func setError(span trace.Span, err error, description string) {
+	if err != nil {
+		span.RecordError(err)
+		description = err.Error()
+	}
+
+	span.SetStatus(codes.Error, description)
+}
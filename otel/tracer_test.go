@@ -0,0 +1,201 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gosteps "github.com/TanmoySG/go-steps"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errTransient = errors.New("transient error")
+
+func attributeOf(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func spansByName(spans tracetest.SpanStubs) map[string][]tracetest.SpanStub {
+	byName := map[string][]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+
+	return byName
+}
+
+func Test_Tracer(t *testing.T) {
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := NewTracer(WithTracerProvider(provider))
+
+	attempts := 0
+	var childSpan trace.SpanContext
+
+	root := gosteps.NewStepsProcessor(gosteps.Steps{
+		{
+			Name: "fetch",
+			Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+				attempts += 1
+				if attempts < 2 {
+					return gosteps.MarkStateError().WithError(errTransient)
+				}
+
+				_, span := provider.Tracer("test").Start(c.Context(), "child")
+				childSpan = span.SpanContext()
+				span.End()
+
+				return gosteps.MarkStateComplete()
+			},
+			StepOpts: gosteps.StepOpts{
+				MaxRunAttempts: 3,
+				RetryAllErrors: true,
+			},
+			Branches: &gosteps.Branches{
+				DefaultBranch: "store",
+				Branches: []gosteps.Branch{
+					{
+						BranchName: "store",
+						Steps: gosteps.Steps{
+							{
+								Name: "store",
+								Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+									return gosteps.MarkStateFailed()
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	ctx := tracer.Use(gosteps.NewGoStepsContext())
+	report := tracer.Execute(context.Background(), root, ctx)
+
+	assert.Equal(t, gosteps.StepStateFailed, report.Outcome)
+
+	spans := spansByName(exporter.GetSpans())
+
+	chain := spans["chain root"][0]
+	assert.Equal(t, "StepStateFailed", attributeOf(chain, ChainOutcomeKey).AsString())
+	assert.Equal(t, codes.Error, chain.Status.Code)
+
+	fetch := spans["step fetch"][0]
+	assert.Equal(t, chain.SpanContext.SpanID(), fetch.Parent.SpanID())
+	assert.Equal(t, "StepStateComplete", attributeOf(fetch, StepStateKey).AsString())
+	assert.Equal(t, int64(2), attributeOf(fetch, RunCountKey).AsInt64())
+	assert.Equal(t, int64(3), attributeOf(fetch, MaxRunAttemptsKey).AsInt64())
+	assert.Len(t, fetch.Events, 1)
+	assert.Equal(t, "retry", fetch.Events[0].Name)
+
+	// the branch is resolved once the span of the step is ended, it is an event of the chain
+	assert.Len(t, chain.Events, 2)
+	assert.Equal(t, "branch resolved", chain.Events[0].Name)
+	assert.Contains(t, chain.Events[0].Attributes, StepNameKey.String("fetch"))
+	assert.Contains(t, chain.Events[0].Attributes, BranchNameKey.String("store"))
+
+	fetchAttempts := spans["attempt fetch"]
+	assert.Len(t, fetchAttempts, 2)
+	for i, attempt := range fetchAttempts {
+		assert.Equal(t, fetch.SpanContext.SpanID(), attempt.Parent.SpanID())
+		assert.Equal(t, int64(i+1), attributeOf(attempt, AttemptKey).AsInt64())
+	}
+	assert.Equal(t, codes.Error, fetchAttempts[0].Status.Code)
+	assert.Equal(t, errTransient.Error(), fetchAttempts[0].Status.Description)
+
+	// spans created in the step function are children of the span of the attempt
+	child := spans["child"][0]
+	assert.Equal(t, childSpan.SpanID(), child.SpanContext.SpanID())
+	assert.Equal(t, fetchAttempts[1].SpanContext.SpanID(), child.Parent.SpanID())
+
+	store := spans["step store"][0]
+	assert.Equal(t, chain.SpanContext.SpanID(), store.Parent.SpanID())
+	assert.Equal(t, "StepStateFailed", attributeOf(store, StepStateKey).AsString())
+	assert.Equal(t, codes.Error, store.Status.Code)
+}
+
+func Test_Tracer_Parallel(t *testing.T) {
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := NewTracer(WithTracerProvider(provider))
+
+	step := func(c gosteps.GoStepsCtx) gosteps.StepResult {
+		return gosteps.MarkStateComplete()
+	}
+
+	root := gosteps.NewStepsProcessor(gosteps.Steps{
+		{
+			Name: "group",
+			Parallel: &gosteps.Parallel{
+				Steps: gosteps.Steps{
+					{Name: "step1", Function: step},
+					{Name: "step2", Function: step},
+				},
+			},
+		},
+	})
+
+	report := tracer.Execute(context.Background(), root, tracer.Use(gosteps.NewGoStepsContext()))
+	assert.Equal(t, gosteps.StepStateComplete, report.Outcome)
+
+	spans := spansByName(exporter.GetSpans())
+	groupAttempt := spans["attempt group"][0]
+
+	for _, name := range []string{"step step1", "step step2"} {
+		assert.Len(t, spans[name], 1)
+		assert.Equal(t, groupAttempt.SpanContext.SpanID(), spans[name][0].Parent.SpanID())
+	}
+}
+
+func Test_Tracer_SubWorkflow(t *testing.T) {
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := NewTracer(WithTracerProvider(provider))
+
+	auth := &gosteps.Branch{
+		BranchName: "auth",
+		Steps: gosteps.Steps{
+			{
+				Name: "authenticate",
+				Function: func(c gosteps.GoStepsCtx) gosteps.StepResult {
+					return gosteps.MarkStateComplete()
+				},
+			},
+		},
+	}
+
+	root := gosteps.NewStepsProcessor(gosteps.Steps{
+		{Name: "login", SubWorkflow: &gosteps.SubWorkflow{Branch: auth}},
+		{Name: "impersonate", SubWorkflow: &gosteps.SubWorkflow{Branch: auth}},
+	})
+
+	report := tracer.Execute(context.Background(), root, tracer.Use(gosteps.NewGoStepsContext()))
+	assert.Equal(t, gosteps.StepStateComplete, report.Outcome)
+
+	spans := spansByName(exporter.GetSpans())
+
+	// the attempts of the steps of the sub-workflows are children of the spans of their steps
+	for _, name := range []string{"login/auth/authenticate", "impersonate/auth/authenticate"} {
+		assert.Len(t, spans["step "+name], 1)
+		assert.Len(t, spans["attempt "+name], 1)
+
+		step, attempt := spans["step "+name][0], spans["attempt "+name][0]
+		assert.Equal(t, step.SpanContext.SpanID(), attempt.Parent.SpanID())
+		assert.Equal(t, name, attributeOf(attempt, StepNameKey).AsString())
+	}
+}